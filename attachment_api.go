@@ -0,0 +1,284 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/blobstore"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	attachmentFormField   = "file"
+	maxAttachmentBytes    = 25 << 20
+	maxAttachmentName     = 255
+	attachmentUploadError = "could not store attachment at this time"
+	attachmentNotFound    = "could not find attachment"
+	// attachments of scheduled messages are protected by their foreign key
+	foreignKeyViolationCode = "23503"
+)
+
+type PublicAttachment struct {
+	AttachmentID pgtype.UUID `json:"attachment_id"`
+	FileName     string      `json:"file_name"`
+	MimeType     string      `json:"mime_type"`
+	SizeBytes    int64       `json:"size_bytes"`
+	Checksum     string      `json:"checksum"`
+	Width        pgtype.Int4 `json:"width"`
+	Height       pgtype.Int4 `json:"height"`
+	URL          string      `json:"url"`
+}
+
+// attachmentKey is where the file of an attachment is stored, forwarded
+// attachments keep the blob of the original
+func attachmentKey(blobId pgtype.UUID) string {
+	return "attachments/" + uuidString(blobId)
+}
+
+func attachmentURL(attachmentId pgtype.UUID) string {
+	return fmt.Sprintf("%s/message/attachment/%s", apiV1Prefix, uuidString(attachmentId))
+}
+
+func convertToPublicAttachment(a database.Attachment) PublicAttachment {
+	return PublicAttachment{
+		AttachmentID: a.AttachmentID,
+		FileName:     a.FileName,
+		MimeType:     a.MimeType,
+		SizeBytes:    a.SizeBytes,
+		Checksum:     a.Checksum,
+		Width:        a.Width,
+		Height:       a.Height,
+		URL:          attachmentURL(a.AttachmentID),
+	}
+}
+
+func getAttachmentIdParam(r *http.Request) (pgtype.UUID, error) {
+	attachmentId := pgtype.UUID{}
+	err := attachmentId.Scan(chi.URLParam(r, "attachment_id"))
+	return attachmentId, err
+}
+
+// attachmentName keeps only the base name of the uploaded file
+func attachmentName(header *multipart.FileHeader, mtype *mimetype.MIME) (string, bool) {
+	name := strings.TrimSpace(filepath.Base(header.Filename))
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "file" + mtype.Extension()
+	}
+	if !utf8.ValidString(name) || len(name) > maxAttachmentName {
+		return "", false
+	}
+	return name, true
+}
+
+// inspectAttachment computes the checksum and size of the upload, sniffs its
+// content type and reads the dimensions of images
+func inspectAttachment(file multipart.File) (string, int64, *mimetype.MIME, pgtype.Int4, pgtype.Int4, error) {
+	width, height := pgtype.Int4{}, pgtype.Int4{}
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return "", 0, nil, width, height, err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", 0, nil, width, height, err
+	}
+	// never trust the client provided content type
+	mtype, err := mimetype.DetectReader(file)
+	if err != nil {
+		return "", 0, nil, width, height, err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return "", 0, nil, width, height, err
+	}
+	if strings.HasPrefix(mtype.String(), "image/") {
+		config, _, err := image.DecodeConfig(file)
+		if err == nil {
+			width = pgtype.Int4{Int32: int32(config.Width), Valid: true}
+			height = pgtype.Int4{Int32: int32(config.Height), Valid: true}
+		}
+		_, err = file.Seek(0, io.SeekStart)
+		if err != nil {
+			return "", 0, nil, width, height, err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, mtype, width, height, nil
+}
+
+// handleUploadAttachment stores a file that can be sent in a message of type
+// attachment. Until then only the uploader can see it.
+func handleUploadAttachment(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	slog.Info("uploading attachment", "user_id", user.UserID, "user_name", user.Username)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentBytes+(64<<10))
+	file, header, err := r.FormFile(attachmentFormField)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			render.RespondFailure(w, http.StatusRequestEntityTooLarge, "attachment can be at most 25MB")
+			return
+		}
+		render.RespondFailure(w, http.StatusBadRequest, "please upload the attachment in the file field")
+		return
+	}
+	defer file.Close()
+	if header.Size > maxAttachmentBytes {
+		render.RespondFailure(w, http.StatusRequestEntityTooLarge, "attachment can be at most 25MB")
+		return
+	}
+	checksum, size, mtype, width, height, err := inspectAttachment(file)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not read uploaded attachment")
+		return
+	}
+	fileName, ok := attachmentName(header, mtype)
+	if !ok {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid attachment file name")
+		return
+	}
+
+	attachmentId, err := newUUID()
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Blobs.Put(r.Context(), attachmentKey(attachmentId), file, size, mtype.String())
+	if err != nil {
+		slog.Error("could not store attachment blob", "attachment_id", attachmentId, "error", err)
+		render.RespondFailure(w, http.StatusInsufficientStorage, attachmentUploadError)
+		return
+	}
+
+	queries := database.New(apiCfg.ConnPool)
+	attachment, err := queries.CreateAttachment(r.Context(), database.CreateAttachmentParams{
+		AttachmentID: attachmentId,
+		OwnerPvtID:   user.PvtID,
+		FileName:     fileName,
+		MimeType:     mtype.String(),
+		SizeBytes:    size,
+		Checksum:     checksum,
+		Width:        width,
+		Height:       height,
+		CreatedAt:    time.Now().UTC(),
+		BlobID:       attachmentId,
+	})
+	if err != nil {
+		slog.Error("could not create attachment", "attachment_id", attachmentId, "error", err)
+		err = apiCfg.Blobs.Delete(r.Context(), attachmentKey(attachmentId))
+		if err != nil {
+			slog.Warn("could not delete attachment blob", "attachment_id", attachmentId, "error", err)
+		}
+		render.RespondFailure(w, http.StatusInsufficientStorage, attachmentUploadError)
+		return
+	}
+	render.RespondSuccess(w, http.StatusCreated, convertToPublicAttachment(attachment))
+}
+
+// handleGetAttachment sends the file to the uploader or to the participants
+// of the message it was sent in
+func handleGetAttachment(w http.ResponseWriter, r *http.Request) {
+	attachmentId, err := getAttachmentIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid attachment id")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("fetching attachment", "user_id", user.UserID, "attachment_id", attachmentId)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	attachment, err := queries.GetAttachmentForUser(r.Context(), database.GetAttachmentForUserParams{
+		AttachmentID: attachmentId,
+		CallerPvtID:  user.PvtID,
+		Now:          timestampNow(),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondFailure(w, http.StatusNotFound, attachmentNotFound)
+		return
+	} else if err != nil {
+		slog.Error("could not fetch attachment", "attachment_id", attachmentId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	blob, err := apiCfg.Blobs.Get(r.Context(), attachmentKey(attachment.BlobID))
+	if errors.Is(err, blobstore.ErrNotFound) {
+		render.RespondFailure(w, http.StatusNotFound, attachmentNotFound)
+		return
+	} else if err != nil {
+		slog.Error("could not fetch attachment blob", "attachment_id", attachmentId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("content-type", attachment.MimeType)
+	w.Header().Set("content-length", strconv.FormatInt(attachment.SizeBytes, 10))
+	w.Header().Set("content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	w.Header().Set("x-content-type-options", "nosniff")
+	w.Header().Set("cache-control", "private, max-age=86400")
+	w.Header().Set("etag", `"`+attachment.Checksum+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, err = io.Copy(w, blob)
+	if err != nil {
+		slog.Warn("could not send attachment", "attachment_id", attachmentId, "error", err)
+	}
+}
+
+// handleDeleteAttachment discards an upload that was never sent
+func handleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	attachmentId, err := getAttachmentIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid attachment id")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("deleting attachment", "user_id", user.UserID, "attachment_id", attachmentId)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	attachment, err := queries.DeleteUnattachedAttachment(r.Context(), database.DeleteUnattachedAttachmentParams{
+		AttachmentID: attachmentId,
+		OwnerPvtID:   user.PvtID,
+	})
+	var pgErr *pgconn.PgError
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondFailure(w, http.StatusNotFound, attachmentNotFound)
+		return
+	} else if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolationCode {
+		render.RespondFailure(w, http.StatusConflict, "attachment is used by a scheduled message")
+		return
+	} else if err != nil {
+		slog.Error("could not delete attachment", "attachment_id", attachmentId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	err = apiCfg.Blobs.Delete(r.Context(), attachmentKey(attachment.BlobID))
+	if err != nil {
+		slog.Warn("could not delete attachment blob", "attachment_id", attachmentId, "error", err)
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToPublicAttachment(attachment))
+}
@@ -49,7 +49,7 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 			slog.Error("error with validator definition", "error", err)
 			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
 		} else {
-			render.RespondValidationFailure(w, validationError)
+			render.RespondValidationFailure(w, validationError, requestTranslator(r))
 		}
 		return
 	}
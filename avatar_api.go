@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/blobstore"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	avatarFormField    = "avatar"
+	maxAvatarBytes     = 5 << 20
+	maxAvatarDimension = 6000
+	defaultAvatarSize  = 256
+	avatarUploadError  = "could not update avatar at this time"
+)
+
+var (
+	avatarSizes     = []int{64, 128, 256, 512}
+	avatarMimeTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+)
+
+func avatarKey(avatarId pgtype.UUID, size int) string {
+	return fmt.Sprintf("avatars/%s/%d.png", uuidString(avatarId), size)
+}
+
+func avatarURL(u database.User) string {
+	if !u.AvatarID.Valid {
+		return ""
+	}
+	// the avatar id changes with every upload and busts any client caches
+	return fmt.Sprintf("%s/user/%s/avatar?v=%s", apiV1Prefix, uuidString(u.UserID), uuidString(u.AvatarID))
+}
+
+// thumbnail crops the centre square of the image and scales it to size
+func thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		b.Min.X+(b.Dx()-side)/2,
+		b.Min.Y+(b.Dy()-side)/2,
+	))
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
+
+func deleteAvatarBlobs(r *http.Request, blobs blobstore.BlobStore, avatarId pgtype.UUID) {
+	if !avatarId.Valid {
+		return
+	}
+	for _, size := range avatarSizes {
+		err := blobs.Delete(r.Context(), avatarKey(avatarId, size))
+		if err != nil {
+			slog.Warn("could not delete avatar blob", "avatar_id", avatarId, "size", size, "error", err)
+		}
+	}
+}
+
+func handleUploadAvatar(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	slog.Info("uploading avatar", "user_id", user.UserID, "user_name", user.Username)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarBytes+(64<<10))
+	file, _, err := r.FormFile(avatarFormField)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			render.RespondFailure(w, http.StatusRequestEntityTooLarge, "avatar can be at most 5MB")
+			return
+		}
+		render.RespondFailure(w, http.StatusBadRequest, "please upload the image in the avatar field")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxAvatarBytes+1))
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not read uploaded avatar")
+		return
+	} else if len(data) > maxAvatarBytes {
+		render.RespondFailure(w, http.StatusRequestEntityTooLarge, "avatar can be at most 5MB")
+		return
+	}
+	// never trust the client provided content type
+	mtype := mimetype.Detect(data)
+	if !mimetype.EqualsAny(mtype.String(), avatarMimeTypes...) {
+		render.RespondFailure(w, http.StatusUnsupportedMediaType, "avatar must be a png, jpeg, gif or webp image")
+		return
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode avatar image")
+		return
+	} else if config.Width > maxAvatarDimension || config.Height > maxAvatarDimension {
+		render.RespondFailure(w, http.StatusBadRequest, "avatar dimensions are too large")
+		return
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode avatar image")
+		return
+	}
+
+	avatarId, err := newUUID()
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	for _, size := range avatarSizes {
+		slog.Debug("storing avatar thumbnail", "avatar_id", avatarId, "size", size)
+		buf := bytes.Buffer{}
+		err = png.Encode(&buf, thumbnail(img, size))
+		if err != nil {
+			render.RespondFailure(w, http.StatusInternalServerError, avatarUploadError)
+			return
+		}
+		err = apiCfg.Blobs.Put(r.Context(), avatarKey(avatarId, size), &buf, int64(buf.Len()), "image/png")
+		if err != nil {
+			slog.Error("could not store avatar thumbnail", "avatar_id", avatarId, "error", err)
+			deleteAvatarBlobs(r, apiCfg.Blobs, avatarId)
+			render.RespondFailure(w, http.StatusInsufficientStorage, avatarUploadError)
+			return
+		}
+	}
+
+	queries := database.New(apiCfg.ConnPool)
+	updUser, err := queries.UpdateUserAvatar(r.Context(), database.UpdateUserAvatarParams{
+		AvatarID:  avatarId,
+		UpdatedAt: time.Now().UTC(),
+		PvtID:     user.PvtID,
+	})
+	if err != nil {
+		slog.Error("could not update user avatar", "user_id", user.UserID, "error", err)
+		deleteAvatarBlobs(r, apiCfg.Blobs, avatarId)
+		render.RespondFailure(w, http.StatusInsufficientStorage, avatarUploadError)
+		return
+	}
+	deleteAvatarBlobs(r, apiCfg.Blobs, user.AvatarID)
+	render.RespondSuccess(w, http.StatusOK, convertToPublicUser(updUser))
+}
+
+func handleDeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	slog.Info("deleting avatar", "user_id", user.UserID, "user_name", user.Username)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	updUser, err := queries.UpdateUserAvatar(r.Context(), database.UpdateUserAvatarParams{
+		AvatarID:  pgtype.UUID{},
+		UpdatedAt: time.Now().UTC(),
+		PvtID:     user.PvtID,
+	})
+	if err != nil {
+		slog.Error("could not remove user avatar", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, avatarUploadError)
+		return
+	}
+	deleteAvatarBlobs(r, apiCfg.Blobs, user.AvatarID)
+	render.RespondSuccess(w, http.StatusOK, convertToPublicUser(updUser))
+}
+
+func handleGetAvatar(w http.ResponseWriter, r *http.Request) {
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	size := defaultAvatarSize
+	if val := r.URL.Query().Get("size"); val != "" {
+		size, err = strconv.Atoi(val)
+		if err != nil || !slices.Contains(avatarSizes, size) {
+			render.RespondFailure(w, http.StatusBadRequest, map[string]any{"size": avatarSizes})
+			return
+		}
+	}
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	user, err := queries.GetUserByUuid(r.Context(), userId)
+	if err != nil || !user.AvatarID.Valid {
+		render.RespondFailure(w, http.StatusNotFound, "could not find avatar")
+		return
+	}
+	blob, err := apiCfg.Blobs.Get(r.Context(), avatarKey(user.AvatarID, size))
+	if errors.Is(err, blobstore.ErrNotFound) {
+		render.RespondFailure(w, http.StatusNotFound, "could not find avatar")
+		return
+	} else if err != nil {
+		slog.Error("could not fetch avatar blob", "user_id", userId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("content-type", "image/png")
+	w.Header().Set("cache-control", "public, max-age=86400")
+	w.Header().Set("etag", fmt.Sprintf(`"%s-%d"`, uuidString(user.AvatarID), size))
+	w.WriteHeader(http.StatusOK)
+	_, err = io.Copy(w, blob)
+	if err != nil {
+		slog.Warn("could not send avatar", "user_id", userId, "error", err)
+	}
+}
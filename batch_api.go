@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/internal/linkpreview"
+	"github.com/Suryarpan/chat-api/internal/richtext"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type createMessageBatchData struct {
+	Messages []json.RawMessage `json:"messages" validate:"required,min=1,max=1000"`
+}
+
+// batchMessageData is one message of a batch, attachments and scheduling
+// go through the single message endpoint
+type batchMessageData struct {
+	ToUserId     pgtype.UUID `json:"to_user_id"     validate:"required"`
+	MssgType     string      `json:"mssg_type"      validate:"required,oneof=normal reply reaction"`
+	AttachMssgId int64       `json:"attach_mssg_id" validate:"omitempty,min=1"`
+	MssgBody     string      `json:"mssg_body"      validate:"required,maxgraphemes=4096"`
+	Format       string      `json:"format"         validate:"omitempty,oneof=plain markdown"`
+	TtlSeconds   int32       `json:"ttl_seconds"    validate:"omitempty,min=5,max=7776000"`
+}
+
+type BatchMessageResult struct {
+	Index   int            `json:"index"`
+	Message *PublicMessage `json:"message,omitempty"`
+	Error   any            `json:"error,omitempty"`
+}
+
+type BatchMessageResponse struct {
+	Sent    int                  `json:"sent"`
+	Failed  int                  `json:"failed"`
+	Results []BatchMessageResult `json:"results"`
+}
+
+// batchRecipient caches what every message to the same user needs
+type batchRecipient struct {
+	user database.User
+	ttl  int32
+	err  error
+}
+
+// resolveRecipient looks a recipient up once per batch, the error is the
+// one respondCannotMessage would give for a single message
+func resolveRecipient(ctx context.Context, queries *database.Queries, fromUser database.User, userId pgtype.UUID) batchRecipient {
+	toUser, err := queries.GetUserByUuid(ctx, userId)
+	if err != nil {
+		return batchRecipient{err: err}
+	}
+	err = checkCanMessage(ctx, queries, fromUser, toUser)
+	if err != nil {
+		return batchRecipient{err: err}
+	}
+	convTtl, err := queries.GetConversationTTL(ctx, database.GetConversationTTLParams{
+		UserPvtID:  fromUser.PvtID,
+		OtherPvtID: toUser.PvtID,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return batchRecipient{err: err}
+	}
+	return batchRecipient{user: toUser, ttl: convTtl.TtlSeconds}
+}
+
+func recipientError(err error) string {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return "could not find user to send to"
+	case errors.Is(err, errContactsOnly):
+		return errContactsOnly.Error()
+	default:
+		return internalServerErrorMssg
+	}
+}
+
+// handleCreateMessageBatch sends many messages in one transaction. Every
+// message is validated on its own and the ones that pass are written with
+// COPY, the response reports each message at its index in the request.
+func handleCreateMessageBatch(w http.ResponseWriter, r *http.Request) {
+	data := createMessageBatchData{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&data)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(data)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	fromUser := auth.GetUserData(r)
+	slog.Info("sending message batch", "user_id", fromUser.UserID, "count", len(data.Messages))
+
+	trans := requestTranslator(r)
+	queries := database.New(apiCfg.ConnPool)
+	results := make([]BatchMessageResult, len(data.Messages))
+	items := make([]batchMessageData, len(data.Messages))
+	recipients := make(map[pgtype.UUID]batchRecipient)
+	var replyIds []int64
+	var replyPvtIds []int32
+	for i, raw := range data.Messages {
+		results[i].Index = i
+		err = json.Unmarshal(raw, &items[i])
+		if err != nil {
+			results[i].Error = "could not decode data"
+			continue
+		}
+		items[i].MssgBody = richtext.Clean(items[i].MssgBody)
+		err = apiCfg.Validate.Struct(items[i])
+		if err != nil {
+			validationErrors, ok := err.(validator.ValidationErrors)
+			if !ok {
+				slog.Error("error with validator definition", "error", err)
+				render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+				return
+			}
+			results[i].Error = render.ValidationMessages(validationErrors, trans)
+			continue
+		}
+		toUserId := items[i].ToUserId
+		if _, ok := recipients[toUserId]; !ok {
+			recipients[toUserId] = resolveRecipient(r.Context(), queries, fromUser, toUserId)
+		}
+		if err := recipients[toUserId].err; err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) && !errors.Is(err, errContactsOnly) {
+				slog.Error("could not check recipient privacy", "error", err)
+			}
+			results[i].Error = recipientError(err)
+			continue
+		}
+		if items[i].AttachMssgId != 0 {
+			replyIds = append(replyIds, items[i].AttachMssgId)
+			replyPvtIds = append(replyPvtIds, recipients[toUserId].user.PvtID)
+		}
+	}
+
+	// a dangling reference would fail the whole COPY, so they are checked
+	// here and reported per message instead. Only messages of the
+	// conversation with the item's recipient can be replied to.
+	type replyTarget struct {
+		mssgId           int64
+		counterpartPvtId int32
+	}
+	existing := make(map[replyTarget]bool)
+	if len(replyIds) != 0 {
+		found, err := queries.ListConversationMessageIds(r.Context(), database.ListConversationMessageIdsParams{
+			MssgIds:           replyIds,
+			CounterpartPvtIds: replyPvtIds,
+			UserPvtID:         fromUser.PvtID,
+		})
+		if err != nil {
+			slog.Error("could not check reply targets", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+			return
+		}
+		for _, m := range found {
+			existing[replyTarget{m.MssgID, m.CounterpartPvtID}] = true
+		}
+	}
+	pending := make([]int, 0, len(items))
+	for i, item := range items {
+		if results[i].Error != nil {
+			continue
+		}
+		target := replyTarget{item.AttachMssgId, recipients[item.ToUserId].user.PvtID}
+		if item.AttachMssgId != 0 && !existing[target] {
+			results[i].Error = messageNotFoundError
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	sent := make([]database.MessagePublic, 0, len(pending))
+	if len(pending) != 0 {
+		sent, err = insertMessageBatch(r.Context(), apiCfg, fromUser, items, pending, recipients)
+		if err != nil {
+			slog.Error("could not create message batch", "user_id", fromUser.UserID, "error", err)
+			render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
+			return
+		}
+	}
+
+	response := BatchMessageResponse{Results: results}
+	delivered := make([]sentMessage, 0, len(pending))
+	for n, i := range pending {
+		public := convertToPublicMessage(sent[n], fromUser)
+		response.Results[i].Message = &public
+		m := sentMessage{content: sent[n], fromPvtId: fromUser.PvtID, toPvtId: recipients[items[i].ToUserId].user.PvtID}
+		publishMessage(r.Context(), apiCfg.Hub, m.content, m.fromPvtId, m.toPvtId)
+		delivered = append(delivered, m)
+	}
+	notifyRecipients(r.Context(), apiCfg, delivered)
+	response.Sent = len(pending)
+	response.Failed = len(items) - len(pending)
+	render.RespondSuccess(w, http.StatusOK, response)
+}
+
+// insertMessageBatch copies the pending messages into the message tables,
+// ids are taken from the sequence first so the three tables line up
+func insertMessageBatch(ctx context.Context, apiCfg apiconf.ApiConfig, fromUser database.User, items []batchMessageData, pending []int, recipients map[pgtype.UUID]batchRecipient) ([]database.MessagePublic, error) {
+	c, err := apiCfg.ConnPool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Release()
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := database.New(tx)
+	mssgIds, err := queries.ReserveMessageIds(ctx, int32(len(pending)))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	metas := make([]database.CopyMessageMetaParams, 0, len(pending))
+	types := make([]database.CopyMessageTypesParams, 0, len(pending))
+	texts := make([]database.CopyMessageTextsParams, 0, len(pending))
+	var mentions []database.CopyMessageMentionsParams
+	previews := database.QueueLinkPreviewsParams{CreatedAt: now}
+	sent := make([]database.MessagePublic, 0, len(pending))
+	for n, i := range pending {
+		item := items[i]
+		recipient := recipients[item.ToUserId]
+		ttl := item.TtlSeconds
+		if ttl == 0 {
+			ttl = recipient.ttl
+		}
+		expiresAt := pgtype.Timestamp{}
+		if ttl != 0 {
+			expiresAt = pgtype.Timestamp{Time: now.Add(time.Duration(ttl) * time.Second), Valid: true}
+		}
+		attachMssgId := pgtype.Int8{Int64: item.AttachMssgId, Valid: item.AttachMssgId != 0}
+		// the recipient is the only other participant, so no lookup is needed
+		var itemMentions []database.CopyMessageMentionsParams
+		if recipient.user.PvtID != fromUser.PvtID {
+			itemMentions = matchMentions(mssgIds[n], parseMentions(item.MssgBody), recipient.user)
+			mentions = append(mentions, itemMentions...)
+		}
+		mentionsColumn, err := encodeMentions(itemMentions, recipient.user)
+		if err != nil {
+			return nil, err
+		}
+
+		metas = append(metas, database.CopyMessageMetaParams{
+			MssgID:     mssgIds[n],
+			FromPvtID:  fromUser.PvtID,
+			ToPvtID:    recipient.user.PvtID,
+			MssgStatus: database.MessageStatusSent,
+			CreatedAt:  now,
+			UpdatedAt:  now,
+			ExpiresAt:  expiresAt,
+		})
+		types = append(types, database.CopyMessageTypesParams{
+			MssgID:       mssgIds[n],
+			MssgType:     database.MessageType(item.MssgType),
+			AttachMssgID: attachMssgId,
+		})
+		format := messageFormat(item.Format)
+		mssgHtml, err := renderBody(item.MssgBody, format)
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, database.CopyMessageTextsParams{
+			MssgID:     mssgIds[n],
+			MssgBody:   item.MssgBody,
+			MssgFormat: format,
+			MssgHtml:   mssgHtml,
+		})
+		if link, ok := linkpreview.FirstURL(item.MssgBody); ok {
+			previews.MssgIds = append(previews.MssgIds, mssgIds[n])
+			previews.Urls = append(previews.Urls, link)
+		}
+		sent = append(sent, database.MessagePublic{
+			MssgID:       mssgIds[n],
+			FromUserID:   fromUser.UserID,
+			ToUserID:     recipient.user.UserID,
+			MssgStatus:   database.MessageStatusSent,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			MssgType:     database.MessageType(item.MssgType),
+			AttachMssgID: attachMssgId,
+			MssgBody:     item.MssgBody,
+			MssgFormat:   format,
+			MssgHtml:     mssgHtml,
+			ExpiresAt:    expiresAt,
+			Mentions:     mentionsColumn,
+			FromPvtID:    fromUser.PvtID,
+			ToPvtID:      recipient.user.PvtID,
+		})
+	}
+
+	_, err = queries.CopyMessageMeta(ctx, metas)
+	if err != nil {
+		return nil, err
+	}
+	_, err = queries.CopyMessageTypes(ctx, types)
+	if err != nil {
+		return nil, err
+	}
+	_, err = queries.CopyMessageTexts(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(mentions) != 0 {
+		_, err = queries.CopyMessageMentions(ctx, mentions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(previews.MssgIds) != 0 {
+		err = queries.QueueLinkPreviews(ctx, previews)
+		if err != nil {
+			return nil, err
+		}
+	}
+	delivered := make([]sentMessage, 0, len(sent))
+	for _, m := range sent {
+		delivered = append(delivered, sentMessage{content: m, fromPvtId: m.FromPvtID, toPvtId: m.ToPvtID})
+	}
+	err = queueMessageWebhooks(ctx, queries, delivered)
+	if err != nil {
+		return nil, err
+	}
+	return sent, tx.Commit(ctx)
+}
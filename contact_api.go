@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	friendRequestNotFoundError      = "could not find friend request"
+	insufficientStorageContactError = "could not update contacts at this moment"
+	uniqueViolationCode             = "23505"
+)
+
+type createFriendRequestData struct {
+	ToUserId pgtype.UUID `json:"to_user_id" validate:"required"`
+}
+
+func handleSendFriendRequest(w http.ResponseWriter, r *http.Request) {
+	data := createFriendRequestData{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&data)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+
+	apiCfg := apiconf.GetConfig(r)
+	// validate incoming data
+	err = apiCfg.Validate.Struct(data)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("sending friend request", "user_id", user.UserID, "to_user_id", data.ToUserId)
+
+	queries := database.New(apiCfg.ConnPool)
+	toUser, err := queries.GetUserByUuid(r.Context(), data.ToUserId)
+	if err == nil {
+		toUser, err = visibleToCaller(r.Context(), queries, user, toUser)
+	}
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	if toUser.PvtID == user.PvtID {
+		render.RespondFailure(w, http.StatusBadRequest, "cannot send a friend request to yourself")
+		return
+	}
+	isContact, err := queries.IsContact(r.Context(), database.IsContactParams{
+		OwnerPvtID:   user.PvtID,
+		ContactPvtID: toUser.PvtID,
+	})
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	if isContact {
+		render.RespondFailure(w, http.StatusConflict, "user is already a contact")
+		return
+	}
+
+	fr, err := queries.CreateFriendRequest(r.Context(), database.CreateFriendRequestParams{
+		FromPvtID: user.PvtID,
+		ToPvtID:   toUser.PvtID,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+			render.RespondFailure(w, http.StatusConflict, "a friend request with this user is already pending")
+			return
+		}
+		slog.Error("could not create friend request", "error", err)
+		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageContactError)
+		return
+	}
+	publicFr, err := queries.GetFriendRequestPublic(r.Context(), fr.RequestID)
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusCreated, publicFr)
+}
+
+type listFriendRequestData struct {
+	Direction string `json:"direction" validate:"oneof=incoming outgoing"`
+	pageParams
+}
+
+func handleListFriendRequests(w http.ResponseWriter, r *http.Request) {
+	page, err := getPageParams(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid pagination parameters")
+		return
+	}
+	ld := listFriendRequestData{
+		Direction:  If(r.URL.Query().Has("direction"), r.URL.Query().Get("direction"), "incoming"),
+		pageParams: page,
+	}
+
+	apiCfg := apiconf.GetConfig(r)
+	// validate incoming data
+	err = apiCfg.Validate.Struct(ld)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("listing friend requests", "user_id", user.UserID, "direction", ld.Direction)
+
+	queries := database.New(apiCfg.ConnPool)
+	requests, err := queries.ListPendingFriendRequests(r.Context(), database.ListPendingFriendRequestsParams{
+		Incoming:   ld.Direction == "incoming",
+		PvtID:      user.PvtID,
+		PageLimit:  ld.Limit,
+		PageOffset: ld.Offset,
+	})
+	if err != nil {
+		slog.Error("could not list friend requests", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, newPagedResponse(requests, page))
+}
+
+// transitionFriendRequest moves a pending request to its final state. Only
+// the receiver may accept or decline a request and only the sender may
+// cancel it.
+func transitionFriendRequest(w http.ResponseWriter, r *http.Request, status database.FriendRequestStatus) {
+	requestId, err := strconv.ParseInt(chi.URLParam(r, "request_id"), 10, 64)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid request id")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("updating friend request", "user_id", user.UserID, "request_id", requestId, "status", status)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	fr, err := queries.GetFriendRequest(r.Context(), requestId)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, friendRequestNotFoundError)
+		return
+	}
+	actorPvtId := If(status == database.FriendRequestStatusCancelled, fr.FromPvtID, fr.ToPvtID)
+	if actorPvtId != user.PvtID {
+		render.RespondFailure(w, http.StatusNotFound, friendRequestNotFoundError)
+		return
+	}
+
+	tx, err := apiCfg.ConnPool.Begin(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	txQuery := queries.WithTx(tx)
+	now := time.Now().UTC()
+	fr, err = txQuery.UpdateFriendRequestStatus(r.Context(), database.UpdateFriendRequestStatusParams{
+		RequestStatus: status,
+		UpdatedAt:     now,
+		RequestID:     requestId,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondFailure(w, http.StatusConflict, "friend request is no longer pending")
+		return
+	} else if err != nil {
+		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageContactError)
+		return
+	}
+
+	if status == database.FriendRequestStatusAccepted {
+		slog.Debug("adding users to each others contacts", "request_id", requestId)
+		for _, pair := range [][2]int32{{fr.FromPvtID, fr.ToPvtID}, {fr.ToPvtID, fr.FromPvtID}} {
+			err = txQuery.AddContact(r.Context(), database.AddContactParams{
+				OwnerPvtID:   pair[0],
+				ContactPvtID: pair[1],
+				CreatedAt:    now,
+			})
+			if err != nil {
+				render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageContactError)
+				return
+			}
+		}
+	}
+
+	publicFr, err := txQuery.GetFriendRequestPublic(r.Context(), requestId)
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	err = tx.Commit(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageContactError)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, publicFr)
+}
+
+func handleAcceptFriendRequest(w http.ResponseWriter, r *http.Request) {
+	transitionFriendRequest(w, r, database.FriendRequestStatusAccepted)
+}
+
+func handleDeclineFriendRequest(w http.ResponseWriter, r *http.Request) {
+	transitionFriendRequest(w, r, database.FriendRequestStatusDeclined)
+}
+
+func handleCancelFriendRequest(w http.ResponseWriter, r *http.Request) {
+	transitionFriendRequest(w, r, database.FriendRequestStatusCancelled)
+}
+
+func handleListContacts(w http.ResponseWriter, r *http.Request) {
+	page, err := getPageParams(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid pagination parameters")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(page)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("listing contacts", "user_id", user.UserID)
+
+	queries := database.New(apiCfg.ConnPool)
+	contacts, err := queries.ListContacts(r.Context(), database.ListContactsParams{
+		OwnerPvtID: user.PvtID,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+	})
+	if err != nil {
+		slog.Error("could not list contacts", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	publicContacts := make([]PublicUserDetails, 0, len(contacts))
+	for _, c := range contacts {
+		publicContacts = append(publicContacts, convertToReducedUser(c))
+	}
+	render.RespondSuccess(w, http.StatusOK, newPagedResponse(publicContacts, page))
+}
+
+func handleRemoveContact(w http.ResponseWriter, r *http.Request) {
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("removing contact", "user_id", user.UserID, "contact_id", userId)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	contact, err := queries.GetUserByUuid(r.Context(), userId)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	removed, err := queries.RemoveContact(r.Context(), database.RemoveContactParams{
+		OwnerPvtID:   user.PvtID,
+		ContactPvtID: contact.PvtID,
+	})
+	if err != nil {
+		slog.Error("could not remove contact", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, insufficientStorageContactError)
+		return
+	}
+	if removed == 0 {
+		render.RespondFailure(w, http.StatusNotFound, "user is not in your contacts")
+		return
+	}
+	render.RespondSuccess(w, http.StatusNoContent, nil)
+}
+
+func ContactRouter() *chi.Mux {
+	router := chi.NewMux()
+
+	router.Get("/", handleListContacts)
+	router.Delete("/{user_id}", handleRemoveContact)
+	router.Get("/requests", handleListFriendRequests)
+	router.Post("/requests", handleSendFriendRequest)
+	router.Post("/requests/{request_id}/accept", handleAcceptFriendRequest)
+	router.Post("/requests/{request_id}/decline", handleDeclineFriendRequest)
+	router.Delete("/requests/{request_id}", handleCancelFriendRequest)
+
+	return router
+}
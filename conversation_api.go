@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/internal/ratelimit"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	// typingTimeout is how long a client shows a typing indicator without
+	// a fresh event
+	typingTimeout = 6 * time.Second
+	typingEvent   = "typing"
+)
+
+// a client refreshes its typing state every few seconds, bursts cover
+// quick start and stop toggles
+var typingLimiter = ratelimit.New(time.Second, 5)
+
+// getCounterpart resolves the other side of the conversation in the url,
+// users who blocked the caller are not found
+func getCounterpart(r *http.Request, queries *database.Queries, caller database.User) (database.User, error) {
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		return database.User{}, err
+	}
+	user, err := queries.GetUserByUuid(r.Context(), userId)
+	if err != nil {
+		return database.User{}, err
+	}
+	return visibleToCaller(r.Context(), queries, caller, user)
+}
+
+type typingData struct {
+	Typing bool `json:"typing"`
+}
+
+type TypingEvent struct {
+	FromUserID pgtype.UUID `json:"from_user_id"`
+	Typing     bool        `json:"typing"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+}
+
+// handleTyping relays a typing indicator to the other participant. Nothing
+// is stored, the indicator lapses at expires_at unless the client sends a
+// new one.
+func handleTyping(w http.ResponseWriter, r *http.Request) {
+	// an empty body starts typing
+	data := typingData{Typing: true}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&data)
+	if err != nil && !errors.Is(err, io.EOF) {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+	user := auth.GetUserData(r)
+	if !typingLimiter.Allow(user.PvtID) {
+		render.RespondFailure(w, http.StatusTooManyRequests, "too many typing events")
+		return
+	}
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	toUser, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	err = checkCanMessage(r.Context(), queries, user, toUser)
+	if err != nil {
+		respondCannotMessage(w, err)
+		return
+	}
+
+	event := TypingEvent{
+		FromUserID: user.UserID,
+		Typing:     data.Typing,
+		ExpiresAt:  time.Now().UTC().Add(typingTimeout),
+	}
+	err = apiCfg.Hub.Publish(r.Context(), []int32{toUser.PvtID}, typingEvent, event)
+	if err != nil {
+		slog.Error("could not publish typing event", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusAccepted, event)
+}
+
+type UnreadSummary struct {
+	TotalUnread         int64 `json:"total_unread"`
+	UnreadConversations int64 `json:"unread_conversations"`
+}
+
+// handleGetUnread returns the totals for app badges, the counters are kept
+// up to date by the database as messages are sent and read
+func handleGetUnread(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	slog.Info("fetching unread summary", "user_id", user.UserID)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	summary, err := queries.GetUnreadSummary(r.Context(), user.PvtID)
+	if err != nil {
+		slog.Error("could not fetch unread summary", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, UnreadSummary{
+		TotalUnread:         summary.TotalUnread,
+		UnreadConversations: summary.UnreadConversations,
+	})
+}
+
+type UnreadConversation struct {
+	UserID      pgtype.UUID `json:"user_id"`
+	UnreadCount int32       `json:"unread_count"`
+}
+
+func handleListUnreadConversations(w http.ResponseWriter, r *http.Request) {
+	page, err := getPageParams(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid pagination parameters")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(page)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("listing unread conversations", "user_id", user.UserID)
+
+	queries := database.New(apiCfg.ConnPool)
+	rows, err := queries.ListUnreadConversations(r.Context(), database.ListUnreadConversationsParams{
+		OwnerPvtID: user.PvtID,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+	})
+	if err != nil {
+		slog.Error("could not list unread conversations", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	conversations := make([]UnreadConversation, 0, len(rows))
+	for _, c := range rows {
+		conversations = append(conversations, UnreadConversation{UserID: c.UserID, UnreadCount: c.UnreadCount})
+	}
+	render.RespondSuccess(w, http.StatusOK, newPagedResponse(conversations, page))
+}
+
+// handleMarkConversationRead marks every message the counterpart sent to the
+// caller as read in one statement, the read webhooks are queued with it
+func handleMarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("marking conversation as read", "user_id", user.UserID, "counterpart_id", userId)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := queries.GetUserByUuid(r.Context(), userId)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+
+	c, err := apiCfg.ConnPool.Acquire(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	defer c.Release()
+	tx, err := c.Begin(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	txQueries := queries.WithTx(tx)
+	marked, err := txQueries.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+		UpdatedAt: time.Now().UTC(),
+		ToPvtID:   user.PvtID,
+		FromPvtID: counterpart.PvtID,
+	})
+	if err != nil {
+		slog.Error("could not mark conversation as read", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	err = queueReadWebhooks(r.Context(), txQueries, counterpart.PvtID, marked)
+	if err != nil {
+		slog.Error("could not queue read webhooks", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	err = tx.Commit(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, map[string]int64{"marked_read": int64(len(marked))})
+}
+
+func ConversationRouter() *chi.Mux {
+	router := chi.NewMux()
+
+	router.Get("/", handleListConversationMessages)
+	router.Post("/read", handleMarkConversationRead)
+	router.Post("/typing", handleTyping)
+	router.Get("/draft", handleGetDraft)
+	router.Put("/draft", handleSaveDraft)
+	router.Delete("/draft", handleDeleteDraft)
+	router.Post("/archive", handleArchiveConversation)
+	router.Delete("/archive", handleUnarchiveConversation)
+	router.Post("/mute", handleMuteConversation)
+	router.Delete("/mute", handleUnmuteConversation)
+	router.Get("/pins", handleListPinned)
+	router.Get("/settings", handleGetConversationSettings)
+	router.Get("/ttl", handleGetConversationTTL)
+	router.Put("/ttl", handleSetConversationTTL)
+	router.Delete("/ttl", handleClearConversationTTL)
+
+	return router
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	conversationSettingsEvent = "conversation_settings"
+	notificationEvent         = "notification"
+	notificationPreviewLength = 100
+)
+
+// ConversationSettings is how the caller wants to hear about one
+// conversation, the other participant never sees it
+type ConversationSettings struct {
+	UserID     pgtype.UUID `json:"user_id"`
+	Muted      bool        `json:"muted"`
+	MutedUntil *time.Time  `json:"muted_until,omitempty"`
+	Archived   bool        `json:"archived"`
+	ArchivedAt *time.Time  `json:"archived_at,omitempty"`
+	UpdatedAt  *time.Time  `json:"updated_at,omitempty"`
+}
+
+// isMuted reports whether notifications are held back at the given time, a
+// mute that ran out counts as unmuted without anybody clearing it
+func isMuted(s database.ConversationSetting, at time.Time) bool {
+	return s.Muted && (!s.MutedUntil.Valid || s.MutedUntil.Time.After(at))
+}
+
+func convertToConversationSettings(s database.ConversationSetting, counterpartId pgtype.UUID) ConversationSettings {
+	public := ConversationSettings{
+		UserID:    counterpartId,
+		Muted:     isMuted(s, time.Now().UTC()),
+		Archived:  s.ArchivedAt.Valid,
+		UpdatedAt: &s.UpdatedAt,
+	}
+	if public.Muted && s.MutedUntil.Valid {
+		public.MutedUntil = &s.MutedUntil.Time
+	}
+	if s.ArchivedAt.Valid {
+		public.ArchivedAt = &s.ArchivedAt.Time
+	}
+	return public
+}
+
+// publishConversationSettings syncs a settings change to the other devices
+// of the owner
+func publishConversationSettings(ctx context.Context, apiCfg apiconf.ApiConfig, ownerPvtId int32, s ConversationSettings) {
+	err := apiCfg.Hub.Publish(ctx, []int32{ownerPvtId}, conversationSettingsEvent, s)
+	if err != nil {
+		slog.Warn("could not publish conversation settings", "owner_pvt_id", ownerPvtId, "error", err)
+	}
+}
+
+// Notification announces a new incoming message, the time is in the time
+// zone of the recipient so clients can show it as is
+type Notification struct {
+	MssgID     int64       `json:"mssg_id"`
+	FromUserID pgtype.UUID `json:"from_user_id"`
+	Title      string      `json:"title"`
+	Body       string      `json:"body"`
+	SentAt     time.Time   `json:"sent_at"`
+}
+
+func notificationBody(m database.MessagePublic) string {
+	if m.AttachmentID.Valid && m.MssgBody == "" {
+		return "sent an attachment"
+	}
+	if utf8.RuneCountInString(m.MssgBody) <= notificationPreviewLength {
+		return m.MssgBody
+	}
+	return string([]rune(m.MssgBody)[:notificationPreviewLength]) + "…"
+}
+
+type notificationPair struct {
+	fromPvtId int32
+	toPvtId   int32
+}
+
+// notifyRecipients sends a notification for every sent message unless the
+// recipient muted the conversation. Users and settings are looked up once
+// per conversation so a batch costs a query per recipient, not per message.
+func notifyRecipients(ctx context.Context, apiCfg apiconf.ApiConfig, sent []sentMessage) {
+	queries := database.New(apiCfg.ConnPool)
+	users := make(map[int32]*database.User)
+	muted := make(map[notificationPair]bool)
+	getUser := func(pvtId int32) (*database.User, error) {
+		if u, ok := users[pvtId]; ok {
+			return u, nil
+		}
+		u, err := queries.GetUserById(ctx, pvtId)
+		if err != nil {
+			return nil, err
+		}
+		users[pvtId] = &u
+		return &u, nil
+	}
+
+	now := time.Now().UTC()
+	for _, m := range sent {
+		if m.fromPvtId == m.toPvtId {
+			continue
+		}
+		pair := notificationPair{m.fromPvtId, m.toPvtId}
+		isPairMuted, ok := muted[pair]
+		if !ok {
+			settings, err := queries.GetConversationSettings(ctx, database.GetConversationSettingsParams{
+				OwnerPvtID:       m.toPvtId,
+				CounterpartPvtID: m.fromPvtId,
+			})
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				slog.Warn("could not fetch conversation settings", "owner_pvt_id", m.toPvtId, "error", err)
+				continue
+			}
+			isPairMuted = isMuted(settings, now)
+			muted[pair] = isPairMuted
+		}
+		if isPairMuted {
+			continue
+		}
+		fromUser, err := getUser(m.fromPvtId)
+		var toUser *database.User
+		if err == nil {
+			toUser, err = getUser(m.toPvtId)
+		}
+		if err != nil {
+			slog.Warn("could not fetch users to notify", "mssg_id", m.content.MssgID, "error", err)
+			continue
+		}
+		err = apiCfg.Hub.Publish(ctx, []int32{m.toPvtId}, notificationEvent, Notification{
+			MssgID:     m.content.MssgID,
+			FromUserID: fromUser.UserID,
+			Title:      fromUser.DisplayName,
+			Body:       notificationBody(m.content),
+			SentAt:     m.content.CreatedAt.In(userLocation(*toUser)),
+		})
+		if err != nil {
+			slog.Warn("could not publish notification", "mssg_id", m.content.MssgID, "error", err)
+		}
+	}
+}
+
+func handleGetConversationSettings(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	settings, err := queries.GetConversationSettings(r.Context(), database.GetConversationSettingsParams{
+		OwnerPvtID:       user.PvtID,
+		CounterpartPvtID: counterpart.PvtID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondSuccess(w, http.StatusOK, ConversationSettings{UserID: counterpart.UserID})
+		return
+	} else if err != nil {
+		slog.Error("could not fetch conversation settings", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToConversationSettings(settings, counterpart.UserID))
+}
+
+// respondSettingsChange finishes every settings endpoint, the owner's other
+// devices get the new settings too
+func respondSettingsChange(w http.ResponseWriter, r *http.Request, s database.ConversationSetting, err error, counterpartId pgtype.UUID) {
+	if err != nil {
+		slog.Error("could not change conversation settings", "owner_pvt_id", s.OwnerPvtID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	public := convertToConversationSettings(s, counterpartId)
+	publishConversationSettings(r.Context(), apiconf.GetConfig(r), s.OwnerPvtID, public)
+	render.RespondSuccess(w, http.StatusOK, public)
+}
+
+type muteConversationData struct {
+	Until *time.Time `json:"until" validate:"omitnil,gt"`
+}
+
+// handleMuteConversation stops notifications for the conversation, without
+// an until time the mute lasts until it is removed
+func handleMuteConversation(w http.ResponseWriter, r *http.Request) {
+	data := muteConversationData{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&data)
+	// an empty body mutes indefinitely
+	if err != nil && !errors.Is(err, io.EOF) {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(data)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	slog.Info("muting conversation", "user_id", user.UserID, "counterpart_id", counterpart.UserID)
+
+	mutedUntil := pgtype.Timestamp{}
+	if data.Until != nil {
+		mutedUntil = pgtype.Timestamp{Time: data.Until.UTC(), Valid: true}
+	}
+	settings, err := queries.MuteConversation(r.Context(), database.MuteConversationParams{
+		OwnerPvtID:       user.PvtID,
+		CounterpartPvtID: counterpart.PvtID,
+		MutedUntil:       mutedUntil,
+		UpdatedAt:        time.Now().UTC(),
+	})
+	respondSettingsChange(w, r, settings, err, counterpart.UserID)
+}
+
+func handleUnmuteConversation(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	slog.Info("unmuting conversation", "user_id", user.UserID, "counterpart_id", counterpart.UserID)
+
+	settings, err := queries.UnmuteConversation(r.Context(), database.UnmuteConversationParams{
+		OwnerPvtID:       user.PvtID,
+		CounterpartPvtID: counterpart.PvtID,
+		UpdatedAt:        time.Now().UTC(),
+	})
+	respondSettingsChange(w, r, settings, err, counterpart.UserID)
+}
+
+// handleArchiveConversation hides the conversation from the inbox until a
+// new message arrives, muted conversations stay archived
+func handleArchiveConversation(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	slog.Info("archiving conversation", "user_id", user.UserID, "counterpart_id", counterpart.UserID)
+
+	settings, err := queries.ArchiveConversation(r.Context(), database.ArchiveConversationParams{
+		OwnerPvtID:       user.PvtID,
+		CounterpartPvtID: counterpart.PvtID,
+		ArchivedAt:       timestampNow(),
+	})
+	respondSettingsChange(w, r, settings, err, counterpart.UserID)
+}
+
+func handleUnarchiveConversation(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	slog.Info("unarchiving conversation", "user_id", user.UserID, "counterpart_id", counterpart.UserID)
+
+	settings, err := queries.UnarchiveConversation(r.Context(), database.UnarchiveConversationParams{
+		OwnerPvtID:       user.PvtID,
+		CounterpartPvtID: counterpart.PvtID,
+		UpdatedAt:        time.Now().UTC(),
+	})
+	respondSettingsChange(w, r, settings, err, counterpart.UserID)
+}
+
+type ArchivedConversation struct {
+	UserID      pgtype.UUID `json:"user_id"`
+	ArchivedAt  time.Time   `json:"archived_at"`
+	UnreadCount int32       `json:"unread_count"`
+}
+
+// handleListArchived lists the caller's archived conversations, latest
+// archived first
+func handleListArchived(w http.ResponseWriter, r *http.Request) {
+	page, err := getPageParams(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid pagination parameters")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(page)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("listing archived conversations", "user_id", user.UserID)
+
+	queries := database.New(apiCfg.ConnPool)
+	rows, err := queries.ListArchivedConversations(r.Context(), database.ListArchivedConversationsParams{
+		OwnerPvtID: user.PvtID,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+	})
+	if err != nil {
+		slog.Error("could not list archived conversations", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	conversations := make([]ArchivedConversation, 0, len(rows))
+	for _, c := range rows {
+		conversations = append(conversations, ArchivedConversation{
+			UserID:      c.UserID,
+			ArchivedAt:  c.ArchivedAt.Time,
+			UnreadCount: c.UnreadCount,
+		})
+	}
+	render.RespondSuccess(w, http.StatusOK, newPagedResponse(conversations, page))
+}
@@ -0,0 +1,46 @@
+-- name: CreateAttachment :one
+INSERT INTO attachments (
+    attachment_id, owner_pvt_id, file_name, mime_type, size_bytes, checksum, width, height, created_at, blob_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING *;
+
+-- name: AttachToMessage :one
+UPDATE attachments
+SET mssg_id = $1
+WHERE attachment_id = $2 AND owner_pvt_id = $3 AND mssg_id IS NULL
+RETURNING *;
+
+-- name: GetAttachmentForUser :one
+SELECT a.*
+FROM attachments a
+LEFT JOIN message_meta mm ON mm.mssg_id = a.mssg_id
+WHERE a.attachment_id = sqlc.arg(attachment_id)
+    AND (a.owner_pvt_id = sqlc.arg(caller_pvt_id)
+        OR mm.from_pvt_id = sqlc.arg(caller_pvt_id)
+        OR mm.to_pvt_id = sqlc.arg(caller_pvt_id))
+    AND (mm.expires_at IS NULL OR mm.expires_at > sqlc.arg(now));
+
+-- name: DeleteUnattachedAttachment :one
+DELETE FROM attachments
+WHERE attachment_id = $1 AND owner_pvt_id = $2 AND mssg_id IS NULL
+RETURNING *;
+
+-- name: DeleteMessageAttachments :many
+WITH deleted AS (
+    DELETE FROM attachments
+    WHERE mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[])
+    RETURNING attachment_id, blob_id
+)
+SELECT DISTINCT d.blob_id
+FROM deleted d
+WHERE NOT EXISTS (
+    SELECT 1
+    FROM attachments a
+    WHERE a.blob_id = d.blob_id AND a.attachment_id NOT IN (SELECT attachment_id FROM deleted)
+);
+
+-- name: GetMessageAttachment :one
+SELECT *
+FROM attachments
+WHERE mssg_id = $1;
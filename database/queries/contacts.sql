@@ -0,0 +1,64 @@
+-- name: CreateFriendRequest :one
+INSERT INTO friend_requests (
+    from_pvt_id, to_pvt_id, request_status, created_at, updated_at
+) VALUES (
+    $1, $2, 'pending', $3, $3
+) RETURNING *;
+
+-- name: GetFriendRequest :one
+SELECT *
+FROM friend_requests
+WHERE request_id = $1;
+
+-- name: GetFriendRequestPublic :one
+SELECT fr.request_id, fu.user_id AS from_user_id, tu.user_id AS to_user_id, fr.request_status, fr.created_at, fr.updated_at
+FROM friend_requests fr
+JOIN users fu ON fu.pvt_id = fr.from_pvt_id
+JOIN users tu ON tu.pvt_id = fr.to_pvt_id
+WHERE fr.request_id = $1;
+
+-- name: ListPendingFriendRequests :many
+SELECT fr.request_id, fu.user_id AS from_user_id, tu.user_id AS to_user_id, fr.request_status, fr.created_at, fr.updated_at
+FROM friend_requests fr
+JOIN users fu ON fu.pvt_id = fr.from_pvt_id
+JOIN users tu ON tu.pvt_id = fr.to_pvt_id
+WHERE fr.request_status = 'pending' AND (
+    (sqlc.arg(incoming)::boolean AND fr.to_pvt_id = sqlc.arg(pvt_id)::integer)
+    OR (NOT sqlc.arg(incoming)::boolean AND fr.from_pvt_id = sqlc.arg(pvt_id)::integer)
+)
+ORDER BY fr.created_at DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: UpdateFriendRequestStatus :one
+UPDATE friend_requests
+SET request_status = $1, updated_at = $2
+WHERE request_id = $3 AND request_status = 'pending'
+RETURNING *;
+
+-- name: AddContact :exec
+INSERT INTO contacts (
+    owner_pvt_id, contact_pvt_id, created_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT DO NOTHING;
+
+-- name: RemoveContact :execrows
+DELETE
+FROM contacts
+WHERE (owner_pvt_id = $1 AND contact_pvt_id = $2)
+    OR (owner_pvt_id = $2 AND contact_pvt_id = $1);
+
+-- name: IsContact :one
+SELECT EXISTS (
+    SELECT 1
+    FROM contacts
+    WHERE owner_pvt_id = $1 AND contact_pvt_id = $2
+);
+
+-- name: ListContacts :many
+SELECT u.*
+FROM contacts c
+JOIN users u ON u.pvt_id = c.contact_pvt_id
+WHERE c.owner_pvt_id = $1
+ORDER BY u.username
+LIMIT $2 OFFSET $3;
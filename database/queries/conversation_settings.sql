@@ -0,0 +1,49 @@
+-- name: GetConversationSettings :one
+SELECT *
+FROM conversation_settings
+WHERE owner_pvt_id = $1 AND counterpart_pvt_id = $2;
+
+-- name: MuteConversation :one
+INSERT INTO conversation_settings (
+    owner_pvt_id, counterpart_pvt_id, muted, muted_until, updated_at
+) VALUES (
+    $1, $2, TRUE, $3, $4
+) ON CONFLICT (owner_pvt_id, counterpart_pvt_id) DO UPDATE
+SET muted = TRUE, muted_until = EXCLUDED.muted_until, updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: UnmuteConversation :one
+INSERT INTO conversation_settings (
+    owner_pvt_id, counterpart_pvt_id, updated_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (owner_pvt_id, counterpart_pvt_id) DO UPDATE
+SET muted = FALSE, muted_until = NULL, updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: ArchiveConversation :one
+INSERT INTO conversation_settings (
+    owner_pvt_id, counterpart_pvt_id, archived_at, updated_at
+) VALUES (
+    $1, $2, $3, $3
+) ON CONFLICT (owner_pvt_id, counterpart_pvt_id) DO UPDATE
+SET archived_at = coalesce(conversation_settings.archived_at, EXCLUDED.archived_at), updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: UnarchiveConversation :one
+INSERT INTO conversation_settings (
+    owner_pvt_id, counterpart_pvt_id, updated_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (owner_pvt_id, counterpart_pvt_id) DO UPDATE
+SET archived_at = NULL, updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: ListArchivedConversations :many
+SELECT u.user_id, cs.archived_at, coalesce(cu.unread_count, 0)::integer as unread_count
+FROM conversation_settings cs
+JOIN users u ON u.pvt_id = cs.counterpart_pvt_id
+LEFT JOIN conversation_unread cu ON cu.owner_pvt_id = cs.owner_pvt_id AND cu.counterpart_pvt_id = cs.counterpart_pvt_id
+WHERE cs.owner_pvt_id = $1 AND cs.archived_at IS NOT NULL
+ORDER BY cs.archived_at DESC, cs.counterpart_pvt_id
+LIMIT $2 OFFSET $3;
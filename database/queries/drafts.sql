@@ -0,0 +1,26 @@
+-- name: GetDraft :one
+SELECT *
+FROM conversation_drafts
+WHERE owner_pvt_id = $1 AND counterpart_pvt_id = $2;
+
+-- name: CreateDraft :one
+INSERT INTO conversation_drafts (
+    owner_pvt_id, counterpart_pvt_id, mssg_body, attach_mssg_id, version, updated_at
+) VALUES (
+    $1, $2, $3, $4, 1, $5
+) ON CONFLICT (owner_pvt_id, counterpart_pvt_id) DO NOTHING
+RETURNING *;
+
+-- name: UpdateDraft :one
+UPDATE conversation_drafts
+SET mssg_body = $1, attach_mssg_id = $2, version = version + 1, updated_at = $3
+WHERE owner_pvt_id = $4 AND counterpart_pvt_id = $5 AND version = $6
+RETURNING *;
+
+-- name: ClearDraft :one
+UPDATE conversation_drafts
+SET mssg_body = '', attach_mssg_id = NULL, version = version + 1, updated_at = sqlc.arg(updated_at)
+WHERE owner_pvt_id = sqlc.arg(owner_pvt_id) AND counterpart_pvt_id = sqlc.arg(counterpart_pvt_id)
+    AND (mssg_body <> '' OR attach_mssg_id IS NOT NULL)
+    AND (sqlc.narg(version)::integer IS NULL OR version = sqlc.narg(version))
+RETURNING *;
@@ -0,0 +1,24 @@
+-- name: NotifyEvent :exec
+SELECT pg_notify(sqlc.arg(channel)::text, sqlc.arg(payload)::text);
+
+-- name: StoreRealtimeEvent :one
+INSERT INTO realtime_events (
+    payload, created_at
+) VALUES (
+    $1, $2
+)
+RETURNING event_id;
+
+-- name: GetRealtimeEvent :one
+SELECT payload
+FROM realtime_events
+WHERE event_id = $1;
+
+-- name: DeleteOldRealtimeEvents :execrows
+DELETE FROM realtime_events
+WHERE event_id IN (
+    SELECT event_id
+    FROM realtime_events
+    WHERE created_at < $1
+    LIMIT $2
+);
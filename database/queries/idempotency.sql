@@ -0,0 +1,28 @@
+-- name: ClaimIdempotencyKey :one
+INSERT INTO message_idempotency_keys (
+    from_pvt_id, idempotency_key, request_hash, created_at
+) VALUES (
+    sqlc.arg(from_pvt_id), sqlc.arg(idempotency_key), sqlc.arg(request_hash), sqlc.arg(created_at)
+) ON CONFLICT (from_pvt_id, idempotency_key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash, mssg_id = NULL, schedule_id = NULL, created_at = EXCLUDED.created_at
+WHERE message_idempotency_keys.created_at < sqlc.arg(expired_before)
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT *
+FROM message_idempotency_keys
+WHERE from_pvt_id = $1 AND idempotency_key = $2;
+
+-- name: SetIdempotencyResult :exec
+UPDATE message_idempotency_keys
+SET mssg_id = sqlc.narg(mssg_id), schedule_id = sqlc.narg(schedule_id)
+WHERE from_pvt_id = sqlc.arg(from_pvt_id) AND idempotency_key = sqlc.arg(idempotency_key);
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM message_idempotency_keys
+WHERE ctid IN (
+    SELECT ctid
+    FROM message_idempotency_keys
+    WHERE created_at < $1
+    LIMIT $2
+);
@@ -0,0 +1,41 @@
+-- name: QueueLinkPreviews :exec
+INSERT INTO message_link_previews (
+    mssg_id, url, created_at
+) SELECT unnest(sqlc.arg(mssg_ids)::bigint[]), unnest(sqlc.arg(urls)::text[]), sqlc.arg(created_at)::timestamp;
+
+-- name: ClaimPendingLinkPreviews :many
+UPDATE message_link_previews mlp
+SET leased_until = sqlc.arg(lease_until)
+FROM message_meta mm
+WHERE mm.mssg_id = mlp.mssg_id AND mlp.mssg_id IN (
+    SELECT due.mssg_id
+    FROM message_link_previews due
+    WHERE due.unfurled_at IS NULL AND (due.leased_until IS NULL OR due.leased_until <= sqlc.arg(now))
+    ORDER BY due.created_at
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE OF due SKIP LOCKED
+)
+RETURNING mlp.mssg_id, mlp.url, mm.from_pvt_id, mm.to_pvt_id;
+
+-- name: GetLinkPreview :one
+SELECT *
+FROM link_previews
+WHERE url = sqlc.arg(url) AND fetched_at > sqlc.arg(fresh_after);
+
+-- name: SaveLinkPreview :exec
+INSERT INTO link_previews (
+    url, ok, title, description, image_url, site_name, fetched_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) ON CONFLICT (url) DO UPDATE
+SET ok = EXCLUDED.ok, title = EXCLUDED.title, description = EXCLUDED.description,
+    image_url = EXCLUDED.image_url, site_name = EXCLUDED.site_name, fetched_at = EXCLUDED.fetched_at;
+
+-- name: MarkLinkPreviewsUnfurled :exec
+UPDATE message_link_previews
+SET unfurled_at = sqlc.arg(unfurled_at)
+WHERE mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[]);
+
+-- name: DeleteMessageLinkPreviews :exec
+DELETE FROM message_link_previews
+WHERE mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[]);
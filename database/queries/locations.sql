@@ -0,0 +1,42 @@
+-- name: CreateMessageLocation :exec
+INSERT INTO message_locations (
+    mssg_id, latitude, longitude, accuracy_meters, live_until, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+);
+
+-- name: GetMessageLocation :one
+SELECT *
+FROM message_locations
+WHERE mssg_id = $1;
+
+-- name: UpdateLiveLocation :execrows
+UPDATE message_locations
+SET latitude = sqlc.arg(latitude), longitude = sqlc.arg(longitude),
+    accuracy_meters = sqlc.arg(accuracy_meters), updated_at = sqlc.arg(updated_at)
+WHERE mssg_id = sqlc.arg(mssg_id) AND live_until > sqlc.arg(updated_at);
+
+-- name: StopLiveLocation :execrows
+UPDATE message_locations
+SET live_until = sqlc.arg(now), updated_at = sqlc.arg(now)
+WHERE mssg_id = sqlc.arg(mssg_id) AND live_until > sqlc.arg(now);
+
+-- name: DeleteMessageLocations :exec
+DELETE FROM message_locations
+WHERE mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[]);
+
+-- name: CreateMessageContact :exec
+INSERT INTO message_contacts (
+    mssg_id, contact_pvt_id
+) VALUES (
+    $1, $2
+);
+
+-- name: GetMessageContact :one
+SELECT *
+FROM message_contacts
+WHERE mssg_id = $1;
+
+-- name: DeleteMessageContacts :exec
+DELETE FROM message_contacts
+WHERE mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[]);
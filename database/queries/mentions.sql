@@ -0,0 +1,21 @@
+-- name: CopyMessageMentions :copyfrom
+INSERT INTO message_mentions (
+    mssg_id, mentioned_pvt_id, start_offset, length
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: DeleteMessageMentions :exec
+DELETE FROM message_mentions
+WHERE mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[]);
+
+-- name: ListMentionedMessages :many
+SELECT mp.*
+FROM message_public mp
+WHERE EXISTS (
+        SELECT 1 FROM message_mentions men
+        WHERE men.mssg_id = mp.mssg_id AND men.mentioned_pvt_id = sqlc.arg(mentioned_pvt_id)
+    )
+    AND (mp.expires_at IS NULL OR mp.expires_at > sqlc.arg(now))
+ORDER BY mp.created_at DESC, mp.mssg_id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
@@ -1,8 +1,8 @@
 -- name: CreateMessage :one
 INSERT INTO message_meta (
-    from_pvt_id, to_pvt_id, mssg_status, created_at, updated_at
+    from_pvt_id, to_pvt_id, mssg_status, created_at, updated_at, expires_at, forwarded_from_pvt_id
 ) VALUES (
-    $1, $2, $3, $4, $5
+    $1, $2, $3, $4, $5, $6, $7
 ) RETURNING *;
 
 -- name: CreateMessageType :one
@@ -14,23 +14,147 @@ INSERT INTO message_type_meta (
 
 -- name: CreateMessageText :one
 INSERT INTO message_text (
-    mssg_id, mssg_body
+    mssg_id, mssg_body, mssg_format, mssg_html
 ) VALUES (
-    $1, $2
+    $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: GetMessageById :one
-SELECT mm.*, mtm.mssg_type, mtm.attach_mssg_id, mt.mssg_body
+SELECT mm.*, mtm.mssg_type, mtm.attach_mssg_id, mt.mssg_body, mt.mssg_format
 FROM message_meta mm
 JOIN message_type_meta mtm ON mtm.mssg_id = mm.mssg_id
 JOIN message_text mt ON mt.mssg_id = mm.mssg_id
 WHERE mm.mssg_id = $1;
 
 -- name: GetMessageByIdPublic :one
-SELECT mm.mssg_id, fu.user_id as from_user_id, tu.user_id as to_user_id, mm.mssg_status, mm.created_at, mm.updated_at, mtm.mssg_type, mtm.attach_mssg_id, mt.mssg_body
-FROM message_meta mm
+SELECT *
+FROM message_public
+WHERE mssg_id = $1;
+
+-- name: ListMessagesByIdsPublic :many
+SELECT *
+FROM message_public
+WHERE mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[])
+ORDER BY mssg_id;
+
+-- name: MarkMessageRead :one
+UPDATE message_meta
+SET mssg_status = 'read', updated_at = $1
+WHERE mssg_id = $2 AND to_pvt_id = $3 AND mssg_status <> 'read'
+RETURNING *;
+
+-- name: SearchMessages :many
+SELECT mm.mssg_id, fu.user_id as from_user_id, tu.user_id as to_user_id, mm.created_at, mtm.mssg_type,
+    ts_headline('simple', translate(mt.mssg_body, E'\x02\x03', ''), websearch_to_tsquery('simple', sqlc.arg(query)),
+        E'StartSel=\x02, StopSel=\x03, MaxFragments=2, MaxWords=24, MinWords=8, FragmentDelimiter=" ... "')::text as snippet
+FROM message_text mt
+JOIN message_meta mm ON mm.mssg_id = mt.mssg_id
 JOIN message_type_meta mtm ON mtm.mssg_id = mm.mssg_id
-JOIN message_text mt ON mt.mssg_id = mm.mssg_id
 JOIN users fu ON fu.pvt_id = mm.from_pvt_id
 JOIN users tu ON tu.pvt_id = mm.to_pvt_id
-WHERE mm.mssg_id = $1;
+WHERE to_tsvector('simple', mt.mssg_body) @@ websearch_to_tsquery('simple', sqlc.arg(query))
+    AND (mm.from_pvt_id = sqlc.arg(caller_pvt_id) OR mm.to_pvt_id = sqlc.arg(caller_pvt_id))
+    AND (mm.expires_at IS NULL OR mm.expires_at > sqlc.arg(now))
+    AND (sqlc.narg(counterpart_pvt_id)::integer IS NULL
+        OR (mm.from_pvt_id = sqlc.arg(caller_pvt_id) AND mm.to_pvt_id = sqlc.narg(counterpart_pvt_id))
+        OR (mm.from_pvt_id = sqlc.narg(counterpart_pvt_id) AND mm.to_pvt_id = sqlc.arg(caller_pvt_id)))
+    AND (sqlc.narg(sent_after)::timestamp IS NULL OR mm.created_at >= sqlc.narg(sent_after))
+    AND (sqlc.narg(sent_before)::timestamp IS NULL OR mm.created_at < sqlc.narg(sent_before))
+    AND (sqlc.narg(mssg_type)::message_type IS NULL OR mtm.mssg_type = sqlc.narg(mssg_type))
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (mm.created_at, mm.mssg_id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_mssg_id)::bigint))
+ORDER BY mm.created_at DESC, mm.mssg_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetUnreadSummary :one
+SELECT coalesce(sum(unread_count), 0)::bigint as total_unread,
+    count(*) FILTER (WHERE unread_count > 0) as unread_conversations
+FROM conversation_unread
+WHERE owner_pvt_id = $1;
+
+-- name: ListUnreadConversations :many
+SELECT u.user_id, cu.unread_count
+FROM conversation_unread cu
+JOIN users u ON u.pvt_id = cu.counterpart_pvt_id
+LEFT JOIN conversation_settings cs ON cs.owner_pvt_id = cu.owner_pvt_id AND cs.counterpart_pvt_id = cu.counterpart_pvt_id
+WHERE cu.owner_pvt_id = $1 AND cu.unread_count > 0 AND cs.archived_at IS NULL
+ORDER BY cu.unread_count DESC, cu.counterpart_pvt_id
+LIMIT $2 OFFSET $3;
+
+-- name: MarkConversationRead :many
+UPDATE message_meta
+SET mssg_status = 'read', updated_at = $1
+WHERE to_pvt_id = $2 AND from_pvt_id = $3 AND mssg_status <> 'read'
+RETURNING mssg_id;
+
+-- name: GetConversationTTL :one
+SELECT *
+FROM conversation_ttl
+WHERE low_pvt_id = least(sqlc.arg(user_pvt_id)::integer, sqlc.arg(other_pvt_id)::integer)
+    AND high_pvt_id = greatest(sqlc.arg(user_pvt_id)::integer, sqlc.arg(other_pvt_id)::integer);
+
+-- name: SetConversationTTL :one
+INSERT INTO conversation_ttl (
+    low_pvt_id, high_pvt_id, ttl_seconds, set_by_pvt_id, updated_at
+) VALUES (
+    least(sqlc.arg(user_pvt_id)::integer, sqlc.arg(other_pvt_id)::integer),
+    greatest(sqlc.arg(user_pvt_id)::integer, sqlc.arg(other_pvt_id)::integer),
+    sqlc.arg(ttl_seconds), sqlc.arg(user_pvt_id), sqlc.arg(updated_at)
+) ON CONFLICT (low_pvt_id, high_pvt_id) DO UPDATE
+SET ttl_seconds = EXCLUDED.ttl_seconds, set_by_pvt_id = EXCLUDED.set_by_pvt_id, updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: ClearConversationTTL :execrows
+DELETE FROM conversation_ttl
+WHERE low_pvt_id = least(sqlc.arg(user_pvt_id)::integer, sqlc.arg(other_pvt_id)::integer)
+    AND high_pvt_id = greatest(sqlc.arg(user_pvt_id)::integer, sqlc.arg(other_pvt_id)::integer);
+
+-- name: ClaimExpiredMessages :many
+SELECT mssg_id, from_pvt_id, to_pvt_id
+FROM message_meta
+WHERE expires_at <= $1 AND purged_at IS NULL
+ORDER BY expires_at
+LIMIT $2
+FOR UPDATE SKIP LOCKED;
+
+-- name: PurgeMessageBodies :exec
+UPDATE message_text
+SET mssg_body = '', mssg_html = NULL
+WHERE mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[]);
+
+-- name: MarkMessagesPurged :exec
+UPDATE message_meta
+SET purged_at = sqlc.arg(purged_at)
+WHERE mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[]);
+
+-- name: ReserveMessageIds :many
+SELECT nextval(pg_get_serial_sequence('message_meta', 'mssg_id'))::BIGINT AS mssg_id
+FROM generate_series(1, sqlc.arg(count)::INTEGER);
+
+-- name: ListConversationMessageIds :many
+SELECT mm.mssg_id, c.counterpart_pvt_id::integer as counterpart_pvt_id
+FROM unnest(sqlc.arg(mssg_ids)::BIGINT[], sqlc.arg(counterpart_pvt_ids)::INTEGER[]) AS c(mssg_id, counterpart_pvt_id)
+JOIN message_meta mm ON mm.mssg_id = c.mssg_id
+WHERE (mm.from_pvt_id = sqlc.arg(user_pvt_id) AND mm.to_pvt_id = c.counterpart_pvt_id)
+    OR (mm.from_pvt_id = c.counterpart_pvt_id AND mm.to_pvt_id = sqlc.arg(user_pvt_id));
+
+-- name: CopyMessageMeta :copyfrom
+INSERT INTO message_meta (
+    mssg_id, from_pvt_id, to_pvt_id, mssg_status, created_at, updated_at, expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: CopyMessageTypes :copyfrom
+INSERT INTO message_type_meta (
+    mssg_id, mssg_type, attach_mssg_id
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: CopyMessageTexts :copyfrom
+INSERT INTO message_text (
+    mssg_id, mssg_body, mssg_format, mssg_html
+) VALUES (
+    $1, $2, $3, $4
+);
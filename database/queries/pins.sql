@@ -0,0 +1,31 @@
+-- name: LockConversationPins :exec
+SELECT pg_advisory_xact_lock(least(mm.from_pvt_id, mm.to_pvt_id), greatest(mm.from_pvt_id, mm.to_pvt_id))
+FROM message_meta mm
+WHERE mm.mssg_id = $1;
+
+-- name: PinMessage :execrows
+INSERT INTO message_pins (
+    mssg_id, low_pvt_id, high_pvt_id, pinned_by_pvt_id, created_at
+)
+SELECT mm.mssg_id, least(mm.from_pvt_id, mm.to_pvt_id), greatest(mm.from_pvt_id, mm.to_pvt_id), sqlc.arg(pinned_by_pvt_id), sqlc.arg(created_at)
+FROM message_meta mm
+WHERE mm.mssg_id = sqlc.arg(mssg_id)
+    AND (
+        SELECT count(*)
+        FROM message_pins mp
+        WHERE mp.low_pvt_id = least(mm.from_pvt_id, mm.to_pvt_id)
+            AND mp.high_pvt_id = greatest(mm.from_pvt_id, mm.to_pvt_id)
+    ) < sqlc.arg(max_pins)::integer
+ON CONFLICT (mssg_id) DO NOTHING;
+
+-- name: UnpinMessage :execrows
+DELETE FROM message_pins
+WHERE mssg_id = $1;
+
+-- name: ListPinnedMessages :many
+SELECT mp.*
+FROM message_pins pin
+JOIN message_public mp ON mp.mssg_id = pin.mssg_id
+WHERE pin.low_pvt_id = least(sqlc.arg(user_pvt_id)::integer, sqlc.arg(other_pvt_id)::integer)
+    AND pin.high_pvt_id = greatest(sqlc.arg(user_pvt_id)::integer, sqlc.arg(other_pvt_id)::integer)
+ORDER BY pin.created_at DESC;
@@ -0,0 +1,45 @@
+-- name: CreatePoll :exec
+INSERT INTO polls (
+    mssg_id, multiple_choice, anonymous, closes_at
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: CopyPollOptions :copyfrom
+INSERT INTO poll_options (
+    mssg_id, option_id, option_text
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: GetPollForUpdate :one
+SELECT p.mssg_id, p.multiple_choice, p.anonymous, p.closes_at,
+    (SELECT count(*) FROM poll_options po WHERE po.mssg_id = p.mssg_id)::integer as option_count
+FROM polls p
+WHERE p.mssg_id = $1
+FOR UPDATE;
+
+-- name: VotePoll :execrows
+INSERT INTO poll_votes (
+    mssg_id, option_id, voter_pvt_id, voted_at
+) VALUES (
+    $1, $2, $3, $4
+) ON CONFLICT DO NOTHING;
+
+-- name: ClearOtherPollVotes :execrows
+DELETE FROM poll_votes
+WHERE mssg_id = $1 AND voter_pvt_id = $2 AND option_id <> $3;
+
+-- name: UnvotePoll :execrows
+DELETE FROM poll_votes
+WHERE mssg_id = $1 AND option_id = $2 AND voter_pvt_id = $3;
+
+-- name: ListPollVotesOfUser :many
+SELECT option_id
+FROM poll_votes
+WHERE mssg_id = $1 AND voter_pvt_id = $2
+ORDER BY option_id;
+
+-- name: DeletePolls :exec
+DELETE FROM polls
+WHERE mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[]);
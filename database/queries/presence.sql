@@ -0,0 +1,32 @@
+-- name: UpsertLastActive :exec
+INSERT INTO user_presence (
+    pvt_id, last_active_at
+) SELECT p.pvt_id, p.last_active_at
+FROM unnest(sqlc.arg(pvt_ids)::integer[], sqlc.arg(last_active_ats)::timestamp[]) AS p(pvt_id, last_active_at)
+ON CONFLICT (pvt_id) DO UPDATE
+SET last_active_at = greatest(user_presence.last_active_at, EXCLUDED.last_active_at);
+
+-- name: UpsertPresenceConnections :exec
+INSERT INTO presence_connections (
+    instance_id, pvt_id, connections, heartbeat_at
+) SELECT sqlc.arg(instance_id), p.pvt_id, p.connections, sqlc.arg(heartbeat_at)
+FROM unnest(sqlc.arg(pvt_ids)::integer[], sqlc.arg(connections)::integer[]) AS p(pvt_id, connections)
+ON CONFLICT (instance_id, pvt_id) DO UPDATE
+SET connections = EXCLUDED.connections, heartbeat_at = EXCLUDED.heartbeat_at;
+
+-- name: DeleteInstanceConnections :exec
+DELETE
+FROM presence_connections
+WHERE instance_id = sqlc.arg(instance_id) AND NOT pvt_id = ANY(sqlc.arg(keep_pvt_ids)::integer[]);
+
+-- name: PruneStaleConnections :exec
+DELETE
+FROM presence_connections
+WHERE heartbeat_at < $1;
+
+-- name: GetPresence :many
+SELECT u.pvt_id::integer AS pvt_id, up.last_active_at, coalesce(sum(pc.connections), 0)::integer AS connections
+FROM unnest(sqlc.arg(pvt_ids)::integer[]) AS u(pvt_id)
+LEFT JOIN user_presence up ON up.pvt_id = u.pvt_id
+LEFT JOIN presence_connections pc ON pc.pvt_id = u.pvt_id AND pc.heartbeat_at >= sqlc.arg(fresh_after)
+GROUP BY u.pvt_id, up.last_active_at;
@@ -0,0 +1,48 @@
+-- name: CreateScheduledMessage :one
+INSERT INTO scheduled_messages (
+    from_pvt_id, to_pvt_id, mssg_type, attach_mssg_id, attachment_id, mssg_body, send_at, created_at, updated_at, ttl_seconds, mssg_format
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+) RETURNING *;
+
+-- name: ListScheduledMessages :many
+SELECT sm.*, tu.user_id as to_user_id
+FROM scheduled_messages sm
+JOIN users tu ON tu.pvt_id = sm.to_pvt_id
+WHERE sm.from_pvt_id = $1 AND sm.status <> 'sent'
+ORDER BY sm.send_at, sm.schedule_id
+LIMIT $2 OFFSET $3;
+
+-- name: RescheduleMessage :one
+UPDATE scheduled_messages
+SET send_at = $1, status = 'pending', failure_reason = NULL, updated_at = $2
+WHERE schedule_id = $3 AND from_pvt_id = $4 AND status <> 'sent'
+RETURNING *;
+
+-- name: CancelScheduledMessage :one
+DELETE FROM scheduled_messages
+WHERE schedule_id = $1 AND from_pvt_id = $2 AND status <> 'sent'
+RETURNING *;
+
+-- name: ClaimDueScheduledMessages :many
+SELECT *
+FROM scheduled_messages
+WHERE status = 'pending' AND send_at <= $1
+ORDER BY send_at, schedule_id
+LIMIT $2
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkScheduledSent :exec
+UPDATE scheduled_messages
+SET status = 'sent', mssg_id = $1, attachment_id = NULL, updated_at = $2
+WHERE schedule_id = $3;
+
+-- name: MarkScheduledFailed :exec
+UPDATE scheduled_messages
+SET status = 'failed', failure_reason = $1, updated_at = $2
+WHERE schedule_id = $3;
+
+-- name: GetScheduledMessage :one
+SELECT *
+FROM scheduled_messages
+WHERE schedule_id = $1 AND from_pvt_id = $2;
@@ -0,0 +1,28 @@
+-- name: StarMessage :exec
+INSERT INTO message_stars (
+    owner_pvt_id, mssg_id, created_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (owner_pvt_id, mssg_id) DO NOTHING;
+
+-- name: UnstarMessage :execrows
+DELETE FROM message_stars
+WHERE owner_pvt_id = $1 AND mssg_id = $2;
+
+-- name: IsMessageStarred :one
+SELECT EXISTS (
+    SELECT 1 FROM message_stars WHERE owner_pvt_id = $1 AND mssg_id = $2
+);
+
+-- name: ListStarredMessageIds :many
+SELECT mssg_id
+FROM message_stars
+WHERE owner_pvt_id = sqlc.arg(owner_pvt_id) AND mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[]);
+
+-- name: ListStarredMessages :many
+SELECT mp.*
+FROM message_stars ms
+JOIN message_public mp ON mp.mssg_id = ms.mssg_id
+WHERE ms.owner_pvt_id = $1
+ORDER BY ms.created_at DESC, ms.mssg_id DESC
+LIMIT $2 OFFSET $3;
@@ -0,0 +1,65 @@
+-- name: GetThreadRootId :one
+WITH RECURSIVE ancestors AS (
+    SELECT mtm.mssg_id, mtm.mssg_type, mtm.attach_mssg_id, 0 as depth
+    FROM message_type_meta mtm
+    WHERE mtm.mssg_id = sqlc.arg(mssg_id)
+    UNION ALL
+    SELECT parent.mssg_id, parent.mssg_type, parent.attach_mssg_id, a.depth + 1
+    FROM ancestors a
+    JOIN message_type_meta parent ON parent.mssg_id = a.attach_mssg_id
+    JOIN message_meta pm ON pm.mssg_id = parent.mssg_id
+    WHERE a.mssg_type = 'reply'
+        AND least(pm.from_pvt_id, pm.to_pvt_id) = sqlc.arg(low_pvt_id)::integer
+        AND greatest(pm.from_pvt_id, pm.to_pvt_id) = sqlc.arg(high_pvt_id)::integer
+)
+SELECT mssg_id
+FROM ancestors
+ORDER BY depth DESC
+LIMIT 1;
+
+-- name: ListThreadReplies :many
+WITH RECURSIVE thread AS (
+    SELECT sqlc.arg(root_id)::bigint as mssg_id
+    UNION ALL
+    SELECT r.mssg_id
+    FROM thread t
+    JOIN message_type_meta r ON r.attach_mssg_id = t.mssg_id AND r.mssg_type = 'reply'
+    JOIN message_meta rm ON rm.mssg_id = r.mssg_id
+    WHERE least(rm.from_pvt_id, rm.to_pvt_id) = sqlc.arg(low_pvt_id)::integer
+        AND greatest(rm.from_pvt_id, rm.to_pvt_id) = sqlc.arg(high_pvt_id)::integer
+)
+SELECT mp.*
+FROM thread th
+JOIN message_public mp ON mp.mssg_id = th.mssg_id
+WHERE mp.mssg_id <> sqlc.arg(root_id)::bigint
+ORDER BY mp.created_at, mp.mssg_id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: ListThreadSummaries :many
+WITH RECURSIVE thread AS (
+    SELECT root.id as root_id, root.id as mssg_id
+    FROM unnest(sqlc.arg(root_ids)::bigint[]) as root(id)
+    UNION ALL
+    SELECT t.root_id, r.mssg_id
+    FROM thread t
+    JOIN message_type_meta r ON r.attach_mssg_id = t.mssg_id AND r.mssg_type = 'reply'
+    JOIN message_meta rm ON rm.mssg_id = r.mssg_id
+    WHERE least(rm.from_pvt_id, rm.to_pvt_id) = sqlc.arg(low_pvt_id)::integer
+        AND greatest(rm.from_pvt_id, rm.to_pvt_id) = sqlc.arg(high_pvt_id)::integer
+)
+SELECT t.root_id::bigint as root_id, count(*)::integer as reply_count, max(mm.created_at)::timestamp as last_reply_at
+FROM thread t
+JOIN message_meta mm ON mm.mssg_id = t.mssg_id
+WHERE t.mssg_id <> t.root_id
+GROUP BY t.root_id;
+
+-- name: ListConversationMessages :many
+SELECT mp.*
+FROM message_public mp
+WHERE ((mp.from_pvt_id = sqlc.arg(user_pvt_id) AND mp.to_pvt_id = sqlc.arg(other_pvt_id))
+        OR (mp.from_pvt_id = sqlc.arg(other_pvt_id) AND mp.to_pvt_id = sqlc.arg(user_pvt_id)))
+    AND (NOT sqlc.arg(exclude_thread_replies)::boolean OR mp.mssg_type <> 'reply')
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (mp.created_at, mp.mssg_id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_mssg_id)::bigint))
+ORDER BY mp.created_at DESC, mp.mssg_id DESC
+LIMIT sqlc.arg(page_limit);
@@ -32,12 +32,87 @@ WHERE pvt_id = $2;
 
 -- name: UpdateUserDetails :one
 UPDATE users
-SET username = $1, display_name = $2, password = $3, updated_at = $4
-WHERE pvt_id = $5
+SET username = $1, display_name = $2, password = $3, updated_at = $4,
+    bio = $5, status_text = $6, status_expires_at = $7, timezone = $8, locale = $9
+WHERE pvt_id = $10
 RETURNING *;
 
 -- name: DeleteUserDetails :one
 DELETE
 FROM users
 WHERE pvt_id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: SearchUsers :many
+SELECT u.*
+FROM users u
+WHERE (
+    u.username ILIKE sqlc.arg(prefix)::text || '%'
+    OR u.display_name ILIKE sqlc.arg(prefix)::text || '%'
+    OR u.username % sqlc.arg(query)::text
+    OR u.display_name % sqlc.arg(query)::text
+) AND NOT EXISTS (
+    SELECT 1
+    FROM user_blocks ub
+    WHERE ub.blocker_pvt_id = u.pvt_id AND ub.blocked_pvt_id = sqlc.arg(caller_pvt_id)
+)
+ORDER BY greatest(similarity(u.username, sqlc.arg(query)::text), similarity(u.display_name, sqlc.arg(query)::text)) DESC, u.username
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: IsBlockedBy :one
+SELECT EXISTS (
+    SELECT 1
+    FROM user_blocks
+    WHERE blocker_pvt_id = $1 AND blocked_pvt_id = $2
+);
+
+-- name: BlockUser :exec
+INSERT INTO user_blocks (
+    blocker_pvt_id, blocked_pvt_id, created_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE
+FROM user_blocks
+WHERE blocker_pvt_id = $1 AND blocked_pvt_id = $2;
+
+-- name: GetUserSettings :one
+SELECT *
+FROM user_settings
+WHERE pvt_id = $1;
+
+-- name: UpsertUserSettings :one
+INSERT INTO user_settings (
+    pvt_id, accept_messages_from, last_seen_visibility, send_read_receipts, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5
+) ON CONFLICT (pvt_id) DO UPDATE
+SET accept_messages_from = EXCLUDED.accept_messages_from,
+    last_seen_visibility = EXCLUDED.last_seen_visibility,
+    send_read_receipts = EXCLUDED.send_read_receipts,
+    updated_at = EXCLUDED.updated_at
+RETURNING *;
+
+-- name: UpdateUserAvatar :one
+UPDATE users
+SET avatar_id = $1, updated_at = $2
+WHERE pvt_id = $3
+RETURNING *;
+
+-- name: ListPresenceTargets :many
+SELECT u.pvt_id, u.user_id, u.last_logged_in,
+    (
+        u.pvt_id = sqlc.arg(caller_pvt_id)
+        OR coalesce(us.last_seen_visibility, 'everyone') = 'everyone'
+        OR (us.last_seen_visibility = 'contacts' AND c.contact_pvt_id IS NOT NULL)
+    )::boolean AS last_seen_visible
+FROM users u
+LEFT JOIN user_settings us ON us.pvt_id = u.pvt_id
+LEFT JOIN contacts c ON c.owner_pvt_id = u.pvt_id AND c.contact_pvt_id = sqlc.arg(caller_pvt_id)
+WHERE u.user_id = ANY(sqlc.arg(user_ids)::uuid[]) AND NOT EXISTS (
+    SELECT 1
+    FROM user_blocks ub
+    WHERE ub.blocker_pvt_id = u.pvt_id AND ub.blocked_pvt_id = sqlc.arg(caller_pvt_id)
+);
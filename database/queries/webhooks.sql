@@ -0,0 +1,125 @@
+-- name: CreateWebhook :one
+INSERT INTO webhook_subscriptions (
+    webhook_id, owner_pvt_id, url, secret, events, created_at, updated_at
+)
+SELECT sqlc.arg(webhook_id), sqlc.arg(owner_pvt_id), sqlc.arg(url), sqlc.arg(secret), sqlc.arg(events)::text[], sqlc.arg(created_at), sqlc.arg(created_at)
+WHERE (
+    SELECT count(*)
+    FROM webhook_subscriptions
+    WHERE owner_pvt_id = sqlc.arg(owner_pvt_id)
+) < sqlc.arg(max_webhooks)::integer
+RETURNING *;
+
+-- name: ListWebhooks :many
+SELECT *
+FROM webhook_subscriptions
+WHERE owner_pvt_id = $1
+ORDER BY created_at, webhook_id;
+
+-- name: GetWebhook :one
+SELECT *
+FROM webhook_subscriptions
+WHERE webhook_id = $1 AND owner_pvt_id = $2;
+
+-- name: UpdateWebhook :one
+UPDATE webhook_subscriptions
+SET url = sqlc.arg(url), events = sqlc.arg(events)::text[], enabled = sqlc.arg(enabled),
+    consecutive_failures = CASE WHEN sqlc.arg(enabled) THEN 0 ELSE consecutive_failures END,
+    disabled_at = CASE WHEN sqlc.arg(enabled) THEN NULL ELSE coalesce(disabled_at, sqlc.arg(updated_at)) END,
+    updated_at = sqlc.arg(updated_at)
+WHERE webhook_id = sqlc.arg(webhook_id) AND owner_pvt_id = sqlc.arg(owner_pvt_id)
+RETURNING *;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhook_subscriptions
+WHERE webhook_id = $1 AND owner_pvt_id = $2;
+
+-- name: ListWebhookOwners :many
+SELECT DISTINCT owner_pvt_id
+FROM webhook_subscriptions
+WHERE owner_pvt_id = ANY(sqlc.arg(owner_pvt_ids)::integer[])
+    AND enabled AND sqlc.arg(event)::text = ANY(events);
+
+-- name: QueueWebhookEvent :execrows
+INSERT INTO webhook_deliveries (
+    webhook_id, event, payload, next_attempt_at, created_at, mssg_id
+) SELECT webhook_id, sqlc.arg(event)::text, sqlc.arg(payload)::jsonb, sqlc.arg(created_at)::timestamp, sqlc.arg(created_at)::timestamp, sqlc.narg(mssg_id)::bigint
+FROM webhook_subscriptions
+WHERE owner_pvt_id = sqlc.arg(owner_pvt_id) AND enabled AND sqlc.arg(event)::text = ANY(events);
+
+-- name: QueueWebhookDelivery :one
+INSERT INTO webhook_deliveries (
+    webhook_id, event, payload, next_attempt_at, created_at
+) VALUES (
+    sqlc.arg(webhook_id), sqlc.arg(event), sqlc.arg(payload), sqlc.arg(created_at), sqlc.arg(created_at)
+)
+RETURNING *;
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries wd
+SET next_attempt_at = sqlc.arg(lease_until)
+FROM webhook_subscriptions ws
+WHERE ws.webhook_id = wd.webhook_id AND wd.delivery_id IN (
+    SELECT due.delivery_id
+    FROM webhook_deliveries due
+    JOIN webhook_subscriptions dws ON dws.webhook_id = due.webhook_id
+    WHERE due.status = 'pending' AND due.next_attempt_at <= sqlc.arg(now) AND dws.enabled
+    ORDER BY due.next_attempt_at
+    LIMIT sqlc.arg(batch_size)
+    FOR UPDATE OF due SKIP LOCKED
+)
+RETURNING wd.delivery_id, wd.webhook_id, wd.event, wd.payload, wd.attempts, wd.created_at, ws.url, ws.secret;
+
+-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET status = sqlc.arg(status), attempts = attempts + 1, next_attempt_at = sqlc.arg(next_attempt_at),
+    last_attempt_at = sqlc.arg(attempted_at), response_status = sqlc.narg(response_status),
+    last_error = sqlc.arg(last_error), delivered_at = sqlc.narg(delivered_at)
+WHERE delivery_id = sqlc.arg(delivery_id);
+
+-- name: ResetWebhookFailures :exec
+UPDATE webhook_subscriptions
+SET consecutive_failures = 0
+WHERE webhook_id = $1 AND consecutive_failures <> 0;
+
+-- name: CountWebhookFailure :one
+UPDATE webhook_subscriptions
+SET consecutive_failures = consecutive_failures + 1,
+    enabled = enabled AND consecutive_failures + 1 < sqlc.arg(max_failures)::integer,
+    disabled_at = CASE
+        WHEN enabled AND consecutive_failures + 1 >= sqlc.arg(max_failures)::integer THEN sqlc.arg(now)::timestamp
+        ELSE disabled_at
+    END
+WHERE webhook_id = sqlc.arg(webhook_id)
+RETURNING *;
+
+-- name: ListWebhookDeliveries :many
+SELECT *
+FROM webhook_deliveries
+WHERE webhook_id = sqlc.arg(webhook_id)
+    AND (sqlc.narg(status)::webhook_delivery_status IS NULL OR status = sqlc.narg(status))
+    AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+        OR (created_at, delivery_id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_delivery_id)::bigint))
+ORDER BY created_at DESC, delivery_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: RetryWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = 'pending', next_attempt_at = sqlc.arg(now)
+WHERE delivery_id = sqlc.arg(delivery_id) AND webhook_id = sqlc.arg(webhook_id) AND status = 'failed'
+RETURNING *;
+
+-- name: DeleteOldWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries
+WHERE delivery_id IN (
+    SELECT delivery_id
+    FROM webhook_deliveries
+    WHERE status <> 'pending' AND created_at < $1
+    LIMIT $2
+);
+
+-- name: PurgeWebhookPayloads :exec
+UPDATE webhook_deliveries
+SET payload = payload - '{mssg_body,mssg_html,mentions,attachment,link_preview,poll,location,contact}'::text[]
+    || '{"expired": true}'::jsonb
+WHERE mssg_id = ANY(sqlc.arg(mssg_ids)::bigint[]);
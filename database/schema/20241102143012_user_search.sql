@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX users_username_trgm_idx ON users USING GIN (username gin_trgm_ops);
+CREATE INDEX users_display_name_trgm_idx ON users USING GIN (display_name gin_trgm_ops);
+
+CREATE TABLE user_blocks (
+    blocker_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    blocked_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_pvt_id, blocked_pvt_id)
+);
+
+CREATE INDEX user_blocks_blocked_idx ON user_blocks (blocked_pvt_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE user_blocks;
+DROP INDEX users_display_name_trgm_idx;
+DROP INDEX users_username_trgm_idx;
+-- +goose StatementEnd
@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE friend_request_status AS ENUM ('pending', 'accepted', 'declined', 'cancelled');
+CREATE TYPE message_privacy AS ENUM ('everyone', 'contacts');
+
+CREATE TABLE friend_requests (
+    request_id BIGSERIAL PRIMARY KEY,
+    from_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    to_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    request_status friend_request_status NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CHECK (from_pvt_id <> to_pvt_id)
+);
+
+-- only one open request is allowed between a pair of users at a time
+CREATE UNIQUE INDEX friend_requests_pending_idx ON friend_requests (
+    least(from_pvt_id, to_pvt_id), greatest(from_pvt_id, to_pvt_id)
+) WHERE request_status = 'pending';
+
+CREATE TABLE contacts (
+    owner_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    contact_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (owner_pvt_id, contact_pvt_id)
+);
+
+CREATE TABLE user_settings (
+    pvt_id INTEGER PRIMARY KEY REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    accept_messages_from message_privacy NOT NULL DEFAULT 'everyone',
+    updated_at TIMESTAMP NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE user_settings;
+DROP TABLE contacts;
+DROP TABLE friend_requests;
+DROP TYPE message_privacy;
+DROP TYPE friend_request_status;
+-- +goose StatementEnd
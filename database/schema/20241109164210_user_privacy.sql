@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE visibility AS ENUM ('everyone', 'contacts', 'nobody');
+
+ALTER TABLE user_settings
+    ADD COLUMN last_seen_visibility visibility NOT NULL DEFAULT 'everyone',
+    ADD COLUMN send_read_receipts BOOLEAN NOT NULL DEFAULT TRUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user_settings
+    DROP COLUMN send_read_receipts,
+    DROP COLUMN last_seen_visibility;
+DROP TYPE visibility;
+-- +goose StatementEnd
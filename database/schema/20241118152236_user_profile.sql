@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN bio VARCHAR(500) NOT NULL DEFAULT '',
+    ADD COLUMN status_text VARCHAR(140) NOT NULL DEFAULT '',
+    ADD COLUMN status_expires_at TIMESTAMP,
+    ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT 'en';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN locale,
+    DROP COLUMN timezone,
+    DROP COLUMN status_expires_at,
+    DROP COLUMN status_text,
+    DROP COLUMN bio;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE user_presence (
+    pvt_id INTEGER PRIMARY KEY REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    last_active_at TIMESTAMP NOT NULL
+);
+
+-- every running instance reports the open real-time connections it holds
+CREATE UNLOGGED TABLE presence_connections (
+    instance_id UUID NOT NULL,
+    pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    connections INTEGER NOT NULL,
+    heartbeat_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (instance_id, pvt_id)
+);
+
+CREATE INDEX presence_connections_pvt_id_idx ON presence_connections (pvt_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE presence_connections;
+DROP TABLE user_presence;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE message_type ADD VALUE 'attachment';
+
+CREATE TABLE attachments (
+    attachment_id UUID PRIMARY KEY,
+    owner_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    mssg_id BIGINT REFERENCES message_meta
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    file_name VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    checksum CHAR(64) NOT NULL,
+    width INTEGER,
+    height INTEGER,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE UNIQUE INDEX attachments_mssg_idx ON attachments (mssg_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE attachments;
+-- postgres cannot drop a value from an enum, 'attachment' stays in message_type
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX message_text_0_search_idx ON message_text_0 USING GIN (to_tsvector('simple', mssg_body));
+CREATE INDEX message_text_1_search_idx ON message_text_1 USING GIN (to_tsvector('simple', mssg_body));
+CREATE INDEX message_text_2_search_idx ON message_text_2 USING GIN (to_tsvector('simple', mssg_body));
+CREATE INDEX message_text_3_search_idx ON message_text_3 USING GIN (to_tsvector('simple', mssg_body));
+CREATE INDEX message_text_4_search_idx ON message_text_4 USING GIN (to_tsvector('simple', mssg_body));
+CREATE INDEX message_text_5_search_idx ON message_text_5 USING GIN (to_tsvector('simple', mssg_body));
+CREATE INDEX message_text_6_search_idx ON message_text_6 USING GIN (to_tsvector('simple', mssg_body));
+CREATE INDEX message_text_7_search_idx ON message_text_7 USING GIN (to_tsvector('simple', mssg_body));
+CREATE INDEX message_text_8_search_idx ON message_text_8 USING GIN (to_tsvector('simple', mssg_body));
+CREATE INDEX message_text_9_search_idx ON message_text_9 USING GIN (to_tsvector('simple', mssg_body));
+CREATE INDEX message_meta_from_created_idx ON message_meta (from_pvt_id, created_at DESC, mssg_id DESC);
+CREATE INDEX message_meta_to_created_idx ON message_meta (to_pvt_id, created_at DESC, mssg_id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX message_meta_to_created_idx;
+DROP INDEX message_meta_from_created_idx;
+DROP INDEX message_text_9_search_idx;
+DROP INDEX message_text_8_search_idx;
+DROP INDEX message_text_7_search_idx;
+DROP INDEX message_text_6_search_idx;
+DROP INDEX message_text_5_search_idx;
+DROP INDEX message_text_4_search_idx;
+DROP INDEX message_text_3_search_idx;
+DROP INDEX message_text_2_search_idx;
+DROP INDEX message_text_1_search_idx;
+DROP INDEX message_text_0_search_idx;
+-- +goose StatementEnd
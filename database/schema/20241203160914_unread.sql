@@ -0,0 +1,97 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE conversation_unread (
+    owner_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    counterpart_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    unread_count INTEGER NOT NULL DEFAULT 0 CHECK (unread_count >= 0),
+    PRIMARY KEY (owner_pvt_id, counterpart_pvt_id)
+);
+
+INSERT INTO conversation_unread (owner_pvt_id, counterpart_pvt_id, unread_count)
+SELECT to_pvt_id, from_pvt_id, count(*)
+FROM message_meta
+WHERE mssg_status <> 'read' AND to_pvt_id <> from_pvt_id
+GROUP BY to_pvt_id, from_pvt_id;
+
+-- the counters follow mssg_status of message_meta through statement level
+-- triggers, so bulk inserts and updates adjust them once per statement
+CREATE FUNCTION unread_after_insert() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO conversation_unread AS cu (owner_pvt_id, counterpart_pvt_id, unread_count)
+    SELECT to_pvt_id, from_pvt_id, count(*)
+    FROM new_rows
+    WHERE mssg_status <> 'read' AND to_pvt_id <> from_pvt_id
+    GROUP BY to_pvt_id, from_pvt_id
+    ON CONFLICT (owner_pvt_id, counterpart_pvt_id) DO UPDATE
+    SET unread_count = cu.unread_count + EXCLUDED.unread_count;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- a counter row exists for every conversation that ever had an unread
+-- message, updates and deletes only need to adjust it
+CREATE FUNCTION unread_after_update() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE conversation_unread cu
+    SET unread_count = cu.unread_count + d.delta
+    FROM (
+        SELECT n.to_pvt_id, n.from_pvt_id,
+            sum((n.mssg_status <> 'read')::integer - (o.mssg_status <> 'read')::integer) AS delta
+        FROM new_rows n
+        JOIN old_rows o ON o.mssg_id = n.mssg_id
+        WHERE n.to_pvt_id <> n.from_pvt_id
+        GROUP BY n.to_pvt_id, n.from_pvt_id
+    ) d
+    WHERE cu.owner_pvt_id = d.to_pvt_id AND cu.counterpart_pvt_id = d.from_pvt_id AND d.delta <> 0;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE FUNCTION unread_after_delete() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE conversation_unread cu
+    SET unread_count = cu.unread_count - d.unread
+    FROM (
+        SELECT to_pvt_id, from_pvt_id, count(*) AS unread
+        FROM old_rows
+        WHERE mssg_status <> 'read' AND to_pvt_id <> from_pvt_id
+        GROUP BY to_pvt_id, from_pvt_id
+    ) d
+    WHERE cu.owner_pvt_id = d.to_pvt_id AND cu.counterpart_pvt_id = d.from_pvt_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER message_meta_unread_insert
+    AFTER INSERT ON message_meta
+    REFERENCING NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION unread_after_insert();
+
+CREATE TRIGGER message_meta_unread_update
+    AFTER UPDATE ON message_meta
+    REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION unread_after_update();
+
+CREATE TRIGGER message_meta_unread_delete
+    AFTER DELETE ON message_meta
+    REFERENCING OLD TABLE AS old_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION unread_after_delete();
+
+CREATE INDEX message_meta_unread_idx ON message_meta (to_pvt_id, from_pvt_id) WHERE mssg_status <> 'read';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX message_meta_unread_idx;
+DROP TRIGGER message_meta_unread_delete ON message_meta;
+DROP TRIGGER message_meta_unread_update ON message_meta;
+DROP TRIGGER message_meta_unread_insert ON message_meta;
+DROP FUNCTION unread_after_delete;
+DROP FUNCTION unread_after_update;
+DROP FUNCTION unread_after_insert;
+DROP TABLE conversation_unread;
+-- +goose StatementEnd
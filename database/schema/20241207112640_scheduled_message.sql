@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE scheduled_status AS ENUM ('pending', 'sent', 'failed');
+
+CREATE TABLE scheduled_messages (
+    schedule_id BIGSERIAL PRIMARY KEY,
+    from_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    to_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    mssg_type message_type NOT NULL,
+    attach_mssg_id BIGINT REFERENCES message_meta
+        ON DELETE CASCADE,
+    attachment_id UUID REFERENCES attachments
+        ON DELETE CASCADE,
+    mssg_body TEXT NOT NULL,
+    send_at TIMESTAMP NOT NULL,
+    status scheduled_status NOT NULL DEFAULT 'pending',
+    mssg_id BIGINT REFERENCES message_meta
+        ON DELETE SET NULL,
+    failure_reason TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX scheduled_messages_due_idx ON scheduled_messages (send_at) WHERE status = 'pending';
+CREATE INDEX scheduled_messages_from_idx ON scheduled_messages (from_pvt_id, send_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE scheduled_messages;
+DROP TYPE scheduled_status;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE message_meta
+ADD COLUMN expires_at TIMESTAMP,
+ADD COLUMN purged_at TIMESTAMP;
+
+CREATE INDEX message_meta_expiry_idx ON message_meta (expires_at)
+WHERE expires_at IS NOT NULL AND purged_at IS NULL;
+
+-- a conversation is stored once, under the smaller pvt_id of its two users
+CREATE TABLE conversation_ttl (
+    low_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    high_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    ttl_seconds INTEGER NOT NULL CHECK (ttl_seconds > 0),
+    set_by_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (low_pvt_id, high_pvt_id),
+    CHECK (low_pvt_id <= high_pvt_id)
+);
+
+ALTER TABLE scheduled_messages
+ADD COLUMN ttl_seconds INTEGER CHECK (ttl_seconds > 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE scheduled_messages
+DROP COLUMN ttl_seconds;
+
+DROP TABLE conversation_ttl;
+
+DROP INDEX message_meta_expiry_idx;
+
+ALTER TABLE message_meta
+DROP COLUMN purged_at,
+DROP COLUMN expires_at;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE message_idempotency_keys (
+    from_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    idempotency_key VARCHAR(128) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    mssg_id BIGINT REFERENCES message_meta
+        ON DELETE CASCADE,
+    schedule_id BIGINT REFERENCES scheduled_messages
+        ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (from_pvt_id, idempotency_key)
+);
+
+CREATE INDEX message_idempotency_keys_created_idx ON message_idempotency_keys (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE message_idempotency_keys;
+-- +goose StatementEnd
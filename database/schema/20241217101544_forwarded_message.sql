@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE message_meta ADD COLUMN forwarded_from_pvt_id INTEGER REFERENCES users
+    ON DELETE SET NULL
+    ON UPDATE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE message_meta DROP COLUMN forwarded_from_pvt_id;
+-- +goose StatementEnd
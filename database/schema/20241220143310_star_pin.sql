@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE message_stars (
+    owner_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    mssg_id BIGINT NOT NULL REFERENCES message_meta
+        ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (owner_pvt_id, mssg_id)
+);
+
+CREATE INDEX message_stars_owner_created_idx ON message_stars (owner_pvt_id, created_at DESC);
+
+CREATE TABLE message_pins (
+    mssg_id BIGINT PRIMARY KEY REFERENCES message_meta
+        ON DELETE CASCADE,
+    low_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    high_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    pinned_by_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    created_at TIMESTAMP NOT NULL,
+    CHECK (low_pvt_id <= high_pvt_id)
+);
+
+CREATE INDEX message_pins_conversation_idx ON message_pins (low_pvt_id, high_pvt_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE message_pins;
+DROP TABLE message_stars;
+-- +goose StatementEnd
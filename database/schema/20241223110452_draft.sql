@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a cleared draft keeps its row so versions never go back
+CREATE TABLE conversation_drafts (
+    owner_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    counterpart_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    mssg_body TEXT NOT NULL,
+    attach_mssg_id BIGINT REFERENCES message_meta
+        ON DELETE SET NULL,
+    version INTEGER NOT NULL CHECK (version > 0),
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (owner_pvt_id, counterpart_pvt_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE conversation_drafts;
+-- +goose StatementEnd
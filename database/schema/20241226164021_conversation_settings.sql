@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+-- muted with no muted_until is muted until the user unmutes
+CREATE TABLE conversation_settings (
+    owner_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    counterpart_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    muted_until TIMESTAMP,
+    archived_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (owner_pvt_id, counterpart_pvt_id)
+);
+
+CREATE INDEX conversation_settings_archived_idx ON conversation_settings (owner_pvt_id, archived_at DESC) WHERE archived_at IS NOT NULL;
+
+-- a new message brings an archived conversation back to the inbox of the
+-- recipient unless they muted it, this covers every way messages are written
+CREATE FUNCTION unarchive_after_insert() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE conversation_settings cs
+    SET archived_at = NULL, updated_at = n.created_at
+    FROM (
+        SELECT to_pvt_id, from_pvt_id, max(created_at) AS created_at
+        FROM new_rows
+        WHERE to_pvt_id <> from_pvt_id
+        GROUP BY to_pvt_id, from_pvt_id
+    ) n
+    WHERE cs.owner_pvt_id = n.to_pvt_id AND cs.counterpart_pvt_id = n.from_pvt_id
+        AND cs.archived_at IS NOT NULL
+        AND NOT (cs.muted AND (cs.muted_until IS NULL OR cs.muted_until > n.created_at));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER message_meta_unarchive_insert
+    AFTER INSERT ON message_meta
+    REFERENCING NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION unarchive_after_insert();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER message_meta_unarchive_insert ON message_meta;
+DROP FUNCTION unarchive_after_insert;
+DROP TABLE conversation_settings;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- offset and length count unicode code points of mssg_body and cover the
+-- whole @username as it was typed
+CREATE TABLE message_mentions (
+    mssg_id BIGINT NOT NULL REFERENCES message_meta
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    mentioned_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    start_offset INTEGER NOT NULL CHECK (start_offset >= 0),
+    length INTEGER NOT NULL CHECK (length > 0),
+    PRIMARY KEY (mssg_id, start_offset)
+);
+
+CREATE INDEX message_mentions_mentioned_idx ON message_mentions (mentioned_pvt_id, mssg_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE message_mentions;
+-- +goose StatementEnd
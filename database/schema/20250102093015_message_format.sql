@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TYPE message_format AS ENUM ('plain', 'markdown');
+
+-- mssg_body stays the raw text the user typed, mssg_html is the sanitized
+-- rendering of markdown bodies
+ALTER TABLE message_text
+    ADD COLUMN mssg_format message_format NOT NULL DEFAULT 'plain',
+    ADD COLUMN mssg_html TEXT;
+
+ALTER TABLE scheduled_messages
+    ADD COLUMN mssg_format message_format NOT NULL DEFAULT 'plain';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE scheduled_messages DROP COLUMN mssg_format;
+ALTER TABLE message_text DROP COLUMN mssg_html, DROP COLUMN mssg_format;
+DROP TYPE message_format;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- previews are cached by url and shared by every message linking to it,
+-- failed fetches are kept too so a dead link is not fetched again and again
+CREATE TABLE link_previews (
+    url TEXT PRIMARY KEY,
+    ok BOOLEAN NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP NOT NULL
+);
+
+-- unfurled_at is NULL until the unfurler got to the message
+CREATE TABLE message_link_previews (
+    mssg_id BIGINT PRIMARY KEY REFERENCES message_meta
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    url TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    unfurled_at TIMESTAMP
+);
+
+CREATE INDEX message_link_previews_pending_idx ON message_link_previews (created_at) WHERE unfurled_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE message_link_previews;
+DROP TABLE link_previews;
+-- +goose StatementEnd
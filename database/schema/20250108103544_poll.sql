@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE message_type ADD VALUE 'poll';
+
+-- the question of a poll is the body of its message, a poll without
+-- closes_at stays open
+CREATE TABLE polls (
+    mssg_id BIGINT PRIMARY KEY REFERENCES message_meta
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    multiple_choice BOOLEAN NOT NULL,
+    anonymous BOOLEAN NOT NULL,
+    closes_at TIMESTAMP
+);
+
+-- options are numbered from 1 in the order they were given
+CREATE TABLE poll_options (
+    mssg_id BIGINT NOT NULL REFERENCES polls
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    option_id INTEGER NOT NULL CHECK (option_id > 0),
+    option_text TEXT NOT NULL,
+    PRIMARY KEY (mssg_id, option_id)
+);
+
+CREATE TABLE poll_votes (
+    mssg_id BIGINT NOT NULL,
+    option_id INTEGER NOT NULL,
+    voter_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    voted_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (mssg_id, option_id, voter_pvt_id),
+    FOREIGN KEY (mssg_id, option_id) REFERENCES poll_options
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX poll_votes_voter_idx ON poll_votes (mssg_id, voter_pvt_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE poll_votes;
+DROP TABLE poll_options;
+DROP TABLE polls;
+-- postgres cannot drop a value from an enum, 'poll' stays in message_type
+-- +goose StatementEnd
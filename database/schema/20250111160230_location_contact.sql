@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TYPE message_type ADD VALUE 'location';
+ALTER TYPE message_type ADD VALUE 'contact';
+
+-- a location with live_until is shared live, the sender keeps moving it
+-- until then
+CREATE TABLE message_locations (
+    mssg_id BIGINT PRIMARY KEY REFERENCES message_meta
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    latitude DOUBLE PRECISION NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    accuracy_meters DOUBLE PRECISION CHECK (accuracy_meters > 0),
+    live_until TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- the card shows the shared user as they are now, not as they were
+CREATE TABLE message_contacts (
+    mssg_id BIGINT PRIMARY KEY REFERENCES message_meta
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    contact_pvt_id INTEGER NOT NULL REFERENCES users
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE message_contacts;
+DROP TABLE message_locations;
+-- postgres cannot drop a value from an enum, 'location' and 'contact' stay
+-- in message_type
+-- +goose StatementEnd
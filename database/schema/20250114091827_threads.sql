@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- threads are walked from a message down to its replies
+CREATE INDEX message_type_meta_reply_idx ON message_type_meta (attach_mssg_id) WHERE mssg_type = 'reply';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX message_type_meta_reply_idx;
+-- +goose StatementEnd
@@ -0,0 +1,127 @@
+-- +goose Up
+-- +goose StatementBegin
+-- purged messages no longer count as unread, the purger marks them a moment
+-- after they expire
+CREATE OR REPLACE FUNCTION unread_after_insert() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO conversation_unread AS cu (owner_pvt_id, counterpart_pvt_id, unread_count)
+    SELECT to_pvt_id, from_pvt_id, count(*)
+    FROM new_rows
+    WHERE mssg_status <> 'read' AND purged_at IS NULL AND to_pvt_id <> from_pvt_id
+    GROUP BY to_pvt_id, from_pvt_id
+    ON CONFLICT (owner_pvt_id, counterpart_pvt_id) DO UPDATE
+    SET unread_count = cu.unread_count + EXCLUDED.unread_count;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- a message leaves the counter of its old conversation and joins the one of
+-- its new, so the deltas stay right even if the participants change. The
+-- recount below leaves a counter for every pair with an unread message, so
+-- only a pair gaining one may be missing its row. A negative counter is
+-- drift and fails the check instead of being hidden.
+CREATE OR REPLACE FUNCTION unread_after_update() RETURNS TRIGGER AS $$
+DECLARE
+    d RECORD;
+BEGIN
+    FOR d IN
+        SELECT c.owner_pvt_id, c.counterpart_pvt_id, sum(c.delta) AS delta
+        FROM (
+            SELECT to_pvt_id AS owner_pvt_id, from_pvt_id AS counterpart_pvt_id, 1 AS delta
+            FROM new_rows
+            WHERE mssg_status <> 'read' AND purged_at IS NULL AND to_pvt_id <> from_pvt_id
+            UNION ALL
+            SELECT to_pvt_id, from_pvt_id, -1
+            FROM old_rows
+            WHERE mssg_status <> 'read' AND purged_at IS NULL AND to_pvt_id <> from_pvt_id
+        ) c
+        GROUP BY c.owner_pvt_id, c.counterpart_pvt_id
+        HAVING sum(c.delta) <> 0
+    LOOP
+        IF d.delta > 0 THEN
+            INSERT INTO conversation_unread AS cu (owner_pvt_id, counterpart_pvt_id, unread_count)
+            VALUES (d.owner_pvt_id, d.counterpart_pvt_id, d.delta)
+            ON CONFLICT (owner_pvt_id, counterpart_pvt_id) DO UPDATE
+            SET unread_count = cu.unread_count + EXCLUDED.unread_count;
+        ELSE
+            UPDATE conversation_unread
+            SET unread_count = unread_count + d.delta
+            WHERE owner_pvt_id = d.owner_pvt_id AND counterpart_pvt_id = d.counterpart_pvt_id;
+        END IF;
+    END LOOP;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION unread_after_delete() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE conversation_unread cu
+    SET unread_count = cu.unread_count - d.unread
+    FROM (
+        SELECT to_pvt_id, from_pvt_id, count(*) AS unread
+        FROM old_rows
+        WHERE mssg_status <> 'read' AND purged_at IS NULL AND to_pvt_id <> from_pvt_id
+        GROUP BY to_pvt_id, from_pvt_id
+    ) d
+    WHERE cu.owner_pvt_id = d.to_pvt_id AND cu.counterpart_pvt_id = d.from_pvt_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- counters that drifted through the old triggers are counted again
+DELETE FROM conversation_unread;
+
+INSERT INTO conversation_unread (owner_pvt_id, counterpart_pvt_id, unread_count)
+SELECT to_pvt_id, from_pvt_id, count(*)
+FROM message_meta
+WHERE mssg_status <> 'read' AND purged_at IS NULL AND to_pvt_id <> from_pvt_id
+GROUP BY to_pvt_id, from_pvt_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION unread_after_insert() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO conversation_unread AS cu (owner_pvt_id, counterpart_pvt_id, unread_count)
+    SELECT to_pvt_id, from_pvt_id, count(*)
+    FROM new_rows
+    WHERE mssg_status <> 'read' AND to_pvt_id <> from_pvt_id
+    GROUP BY to_pvt_id, from_pvt_id
+    ON CONFLICT (owner_pvt_id, counterpart_pvt_id) DO UPDATE
+    SET unread_count = cu.unread_count + EXCLUDED.unread_count;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION unread_after_update() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE conversation_unread cu
+    SET unread_count = cu.unread_count + d.delta
+    FROM (
+        SELECT n.to_pvt_id, n.from_pvt_id,
+            sum((n.mssg_status <> 'read')::integer - (o.mssg_status <> 'read')::integer) AS delta
+        FROM new_rows n
+        JOIN old_rows o ON o.mssg_id = n.mssg_id
+        WHERE n.to_pvt_id <> n.from_pvt_id
+        GROUP BY n.to_pvt_id, n.from_pvt_id
+    ) d
+    WHERE cu.owner_pvt_id = d.to_pvt_id AND cu.counterpart_pvt_id = d.from_pvt_id AND d.delta <> 0;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION unread_after_delete() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE conversation_unread cu
+    SET unread_count = cu.unread_count - d.unread
+    FROM (
+        SELECT to_pvt_id, from_pvt_id, count(*) AS unread
+        FROM old_rows
+        WHERE mssg_status <> 'read' AND to_pvt_id <> from_pvt_id
+        GROUP BY to_pvt_id, from_pvt_id
+    ) d
+    WHERE cu.owner_pvt_id = d.to_pvt_id AND cu.counterpart_pvt_id = d.from_pvt_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- an attachment waiting on a scheduled message can no longer be deleted,
+-- before it took the scheduled message with it. Sent messages hold their
+-- attachment themselves and let go of it.
+UPDATE scheduled_messages
+SET attachment_id = NULL
+WHERE status = 'sent';
+
+ALTER TABLE scheduled_messages
+DROP CONSTRAINT scheduled_messages_attachment_id_fkey,
+ADD CONSTRAINT scheduled_messages_attachment_id_fkey FOREIGN KEY (attachment_id) REFERENCES attachments;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE scheduled_messages
+DROP CONSTRAINT scheduled_messages_attachment_id_fkey,
+ADD CONSTRAINT scheduled_messages_attachment_id_fkey FOREIGN KEY (attachment_id) REFERENCES attachments
+    ON DELETE CASCADE;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- forwarded attachments share the file of the original, the blob goes once
+-- no attachment refers to it anymore
+ALTER TABLE attachments
+ADD COLUMN blob_id UUID;
+
+UPDATE attachments
+SET blob_id = attachment_id;
+
+ALTER TABLE attachments
+ALTER COLUMN blob_id SET NOT NULL;
+
+CREATE INDEX attachments_blob_idx ON attachments (blob_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX attachments_blob_idx;
+
+ALTER TABLE attachments
+DROP COLUMN blob_id;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the unfurler claims links for a while instead of holding a transaction
+-- open while it fetches them
+ALTER TABLE message_link_previews
+ADD COLUMN leased_until TIMESTAMP;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE message_link_previews
+DROP COLUMN leased_until;
+-- +goose StatementEnd
@@ -0,0 +1,70 @@
+-- +goose Up
+-- +goose StatementBegin
+-- message_public is a message the way the api shows it, the queries listing
+-- messages pick theirs from it. The pvt_ids of the participants are only
+-- there to filter on.
+CREATE VIEW message_public AS
+SELECT mm.mssg_id, fu.user_id as from_user_id, tu.user_id as to_user_id, mm.mssg_status, mm.created_at, mm.updated_at, mtm.mssg_type, mtm.attach_mssg_id, mt.mssg_body,
+    mt.mssg_format, mt.mssg_html,
+    coalesce(tus.send_read_receipts, TRUE)::boolean as read_receipts,
+    a.attachment_id, a.file_name, a.mime_type, a.size_bytes, a.checksum, a.width, a.height,
+    mm.expires_at, ffu.user_id as forwarded_from_user_id,
+    EXISTS (SELECT 1 FROM message_pins mp WHERE mp.mssg_id = mm.mssg_id) as pinned,
+    coalesce((
+        SELECT jsonb_agg(jsonb_build_object(
+            'user_id', mu.user_id, 'username', mu.username, 'offset', men.start_offset, 'length', men.length
+        ) ORDER BY men.start_offset)
+        FROM message_mentions men
+        JOIN users mu ON mu.pvt_id = men.mentioned_pvt_id
+        WHERE men.mssg_id = mm.mssg_id
+    ), '[]')::jsonb as mentions,
+    (
+        SELECT jsonb_build_object(
+            'multiple_choice', p.multiple_choice,
+            'anonymous', p.anonymous,
+            'closes_at', p.closes_at AT TIME ZONE 'UTC',
+            'total_voters', (SELECT count(DISTINCT pv.voter_pvt_id) FROM poll_votes pv WHERE pv.mssg_id = p.mssg_id),
+            'options', (
+                SELECT jsonb_agg(jsonb_build_object(
+                    'option_id', po.option_id,
+                    'text', po.option_text,
+                    'votes', (SELECT count(*) FROM poll_votes pv WHERE pv.mssg_id = po.mssg_id AND pv.option_id = po.option_id),
+                    'voters', CASE WHEN p.anonymous THEN '[]'::jsonb ELSE coalesce((
+                        SELECT jsonb_agg(vu.user_id ORDER BY pv.voted_at)
+                        FROM poll_votes pv
+                        JOIN users vu ON vu.pvt_id = pv.voter_pvt_id
+                        WHERE pv.mssg_id = po.mssg_id AND pv.option_id = po.option_id
+                    ), '[]'::jsonb) END
+                ) ORDER BY po.option_id)
+                FROM poll_options po
+                WHERE po.mssg_id = p.mssg_id
+            )
+        )
+        FROM polls p
+        WHERE p.mssg_id = mm.mssg_id
+    )::jsonb as poll,
+    lp.url as preview_url, lp.title as preview_title, lp.description as preview_description,
+    lp.image_url as preview_image_url, lp.site_name as preview_site_name,
+    ml.latitude as location_latitude, ml.longitude as location_longitude, ml.accuracy_meters as location_accuracy_meters,
+    ml.live_until as location_live_until, ml.updated_at as location_updated_at,
+    cu.user_id as contact_user_id, cu.username as contact_username, cu.display_name as contact_display_name,
+    mm.from_pvt_id, mm.to_pvt_id
+FROM message_meta mm
+JOIN message_type_meta mtm ON mtm.mssg_id = mm.mssg_id
+JOIN message_text mt ON mt.mssg_id = mm.mssg_id
+JOIN users fu ON fu.pvt_id = mm.from_pvt_id
+JOIN users tu ON tu.pvt_id = mm.to_pvt_id
+LEFT JOIN user_settings tus ON tus.pvt_id = mm.to_pvt_id
+LEFT JOIN attachments a ON a.mssg_id = mm.mssg_id
+LEFT JOIN users ffu ON ffu.pvt_id = mm.forwarded_from_pvt_id
+LEFT JOIN message_link_previews mlp ON mlp.mssg_id = mm.mssg_id
+LEFT JOIN link_previews lp ON lp.url = mlp.url AND lp.ok
+LEFT JOIN message_locations ml ON ml.mssg_id = mm.mssg_id
+LEFT JOIN message_contacts mc ON mc.mssg_id = mm.mssg_id
+LEFT JOIN users cu ON cu.pvt_id = mc.contact_pvt_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW message_public;
+-- +goose StatementEnd
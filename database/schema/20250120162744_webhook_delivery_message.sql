@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- deliveries carrying a message remember it, so the purger can strip them
+-- when the message expires
+ALTER TABLE webhook_deliveries
+ADD COLUMN mssg_id BIGINT REFERENCES message_meta
+    ON DELETE CASCADE
+    ON UPDATE CASCADE;
+
+UPDATE webhook_deliveries wd
+SET mssg_id = mm.mssg_id
+FROM message_meta mm
+WHERE wd.event IN ('message.created', 'message.read')
+    AND mm.mssg_id = (wd.payload->>'mssg_id')::bigint;
+
+UPDATE webhook_deliveries wd
+SET payload = wd.payload - '{mssg_body,mssg_html,mentions,attachment,link_preview,poll,location,contact}'::text[]
+    || '{"expired": true}'::jsonb
+FROM message_meta mm
+WHERE mm.mssg_id = wd.mssg_id AND mm.purged_at IS NOT NULL;
+
+CREATE INDEX webhook_deliveries_mssg_idx ON webhook_deliveries (mssg_id) WHERE mssg_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries
+DROP COLUMN mssg_id;
+-- +goose StatementEnd
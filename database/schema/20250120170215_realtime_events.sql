@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- events too large for a notification wait here for the other instances,
+-- the notification only carries the id
+CREATE TABLE realtime_events (
+    event_id BIGSERIAL PRIMARY KEY,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX realtime_events_created_idx ON realtime_events (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE realtime_events;
+-- +goose StatementEnd
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	purgerInterval             = 30 * time.Second
+	purgerBatchSize      int32 = 200
+	conversationTtlEvent       = "conversation_ttl"
+	messageExpiredEvent        = "message_expired"
+)
+
+// timestampNow is the current time as a nullable query argument
+func timestampNow() pgtype.Timestamp {
+	return pgtype.Timestamp{Time: time.Now().UTC(), Valid: true}
+}
+
+// ConversationTTL is the disappearing messages setting both participants
+// see, a zero ttl means messages are kept
+type ConversationTTL struct {
+	UserID     pgtype.UUID  `json:"user_id"`
+	TtlSeconds int32        `json:"ttl_seconds"`
+	SetBy      *pgtype.UUID `json:"set_by,omitempty"`
+	UpdatedAt  *time.Time   `json:"updated_at,omitempty"`
+}
+
+func convertToConversationTTL(t database.ConversationTtl, caller, counterpart database.User) ConversationTTL {
+	setBy := caller.UserID
+	if t.SetByPvtID == counterpart.PvtID {
+		setBy = counterpart.UserID
+	}
+	return ConversationTTL{
+		UserID:     counterpart.UserID,
+		TtlSeconds: t.TtlSeconds,
+		SetBy:      &setBy,
+		UpdatedAt:  &t.UpdatedAt,
+	}
+}
+
+// publishConversationTTL tells both participants about the new setting,
+// each from their own side of the conversation
+func publishConversationTTL(ctx context.Context, apiCfg apiconf.ApiConfig, t ConversationTTL, caller, counterpart database.User) {
+	err := apiCfg.Hub.Publish(ctx, []int32{caller.PvtID}, conversationTtlEvent, t)
+	if err == nil {
+		t.UserID = caller.UserID
+		err = apiCfg.Hub.Publish(ctx, []int32{counterpart.PvtID}, conversationTtlEvent, t)
+	}
+	if err != nil {
+		slog.Warn("could not publish conversation ttl", "user_id", caller.UserID, "error", err)
+	}
+}
+
+func handleGetConversationTTL(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	ttl, err := queries.GetConversationTTL(r.Context(), database.GetConversationTTLParams{
+		UserPvtID:  user.PvtID,
+		OtherPvtID: counterpart.PvtID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondSuccess(w, http.StatusOK, ConversationTTL{UserID: counterpart.UserID})
+		return
+	} else if err != nil {
+		slog.Error("could not fetch conversation ttl", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToConversationTTL(ttl, user, counterpart))
+}
+
+type setConversationTTLData struct {
+	TtlSeconds int32 `json:"ttl_seconds" validate:"required,min=5,max=7776000"`
+}
+
+// handleSetConversationTTL turns on disappearing messages for everything
+// sent in the conversation from now on, either side can change it
+func handleSetConversationTTL(w http.ResponseWriter, r *http.Request) {
+	data := setConversationTTLData{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&data)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(data)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	slog.Info("setting conversation ttl", "user_id", user.UserID, "counterpart_id", counterpart.UserID, "ttl_seconds", data.TtlSeconds)
+
+	ttl, err := queries.SetConversationTTL(r.Context(), database.SetConversationTTLParams{
+		UserPvtID:  user.PvtID,
+		OtherPvtID: counterpart.PvtID,
+		TtlSeconds: data.TtlSeconds,
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		slog.Error("could not set conversation ttl", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	public := convertToConversationTTL(ttl, user, counterpart)
+	publishConversationTTL(r.Context(), apiCfg, public, user, counterpart)
+	render.RespondSuccess(w, http.StatusOK, public)
+}
+
+func handleClearConversationTTL(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	slog.Info("clearing conversation ttl", "user_id", user.UserID, "counterpart_id", counterpart.UserID)
+
+	cleared, err := queries.ClearConversationTTL(r.Context(), database.ClearConversationTTLParams{
+		UserPvtID:  user.PvtID,
+		OtherPvtID: counterpart.PvtID,
+	})
+	if err != nil {
+		slog.Error("could not clear conversation ttl", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	public := ConversationTTL{UserID: counterpart.UserID}
+	if cleared != 0 {
+		publishConversationTTL(r.Context(), apiCfg, public, user, counterpart)
+	}
+	render.RespondSuccess(w, http.StatusOK, public)
+}
+
+type MessageExpiredEvent struct {
+	MssgID int64 `json:"mssg_id"`
+}
+
+// runPurger removes the bodies and attachments of expired messages. Reads
+// hide them as soon as they expire, this only reclaims the storage.
+func runPurger(ctx context.Context, apiCfg apiconf.ApiConfig) {
+	runBatches(ctx, "purger", purgerInterval, purgerBatchSize, func(ctx context.Context) (int32, error) {
+		return purgeExpiredMessages(ctx, apiCfg)
+	})
+}
+
+// purgeExpiredMessages tombstones one batch of expired messages, the meta
+// rows stay behind for receipts and counters
+func purgeExpiredMessages(ctx context.Context, apiCfg apiconf.ApiConfig) (int32, error) {
+	c, err := apiCfg.ConnPool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Release()
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := database.New(tx)
+	now := timestampNow()
+	expired, err := queries.ClaimExpiredMessages(ctx, database.ClaimExpiredMessagesParams{
+		ExpiresAt: now,
+		Limit:     purgerBatchSize,
+	})
+	if err != nil || len(expired) == 0 {
+		return 0, err
+	}
+	mssgIds := make([]int64, 0, len(expired))
+	for _, m := range expired {
+		mssgIds = append(mssgIds, m.MssgID)
+	}
+	err = queries.PurgeMessageBodies(ctx, mssgIds)
+	if err != nil {
+		return 0, err
+	}
+	err = queries.DeleteMessageMentions(ctx, mssgIds)
+	if err != nil {
+		return 0, err
+	}
+	err = queries.DeleteMessageLinkPreviews(ctx, mssgIds)
+	if err != nil {
+		return 0, err
+	}
+	err = queries.DeletePolls(ctx, mssgIds)
+	if err != nil {
+		return 0, err
+	}
+	err = queries.DeleteMessageLocations(ctx, mssgIds)
+	if err != nil {
+		return 0, err
+	}
+	err = queries.DeleteMessageContacts(ctx, mssgIds)
+	if err != nil {
+		return 0, err
+	}
+	// the webhook log keeps the same tombstone the api shows, pending
+	// deliveries go out as one
+	err = queries.PurgeWebhookPayloads(ctx, mssgIds)
+	if err != nil {
+		return 0, err
+	}
+	// only blobs no forwarded attachment still shares come back
+	blobIds, err := queries.DeleteMessageAttachments(ctx, mssgIds)
+	if err != nil {
+		return 0, err
+	}
+	err = queries.MarkMessagesPurged(ctx, database.MarkMessagesPurgedParams{
+		PurgedAt: now,
+		MssgIds:  mssgIds,
+	})
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	slog.Debug("purged expired messages", "count", len(expired))
+
+	// the rows are gone, a blob left behind is only wasted space
+	for _, blobId := range blobIds {
+		err = apiCfg.Blobs.Delete(ctx, attachmentKey(blobId))
+		if err != nil {
+			slog.Warn("could not delete attachment blob", "blob_id", blobId, "error", err)
+		}
+	}
+	for _, m := range expired {
+		err = apiCfg.Hub.Publish(ctx, []int32{m.FromPvtID, m.ToPvtID}, messageExpiredEvent, MessageExpiredEvent{MssgID: m.MssgID})
+		if err != nil {
+			slog.Warn("could not publish expired message", "mssg_id", m.MssgID, "error", err)
+		}
+	}
+	return int32(len(expired)), nil
+}
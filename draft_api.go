@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/internal/realtime"
+	"github.com/Suryarpan/chat-api/internal/richtext"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	draftEvent         = "draft"
+	draftConflictError = "draft was changed on another device"
+)
+
+// PublicDraft is the unsent message of a conversation. Every change bumps
+// the version, a client sends the version it last saw along with an edit.
+type PublicDraft struct {
+	UserID       pgtype.UUID `json:"user_id"`
+	MssgBody     string      `json:"mssg_body"`
+	AttachMssgID pgtype.Int8 `json:"attach_mssg_id"`
+	Version      int32       `json:"version"`
+	UpdatedAt    *time.Time  `json:"updated_at,omitempty"`
+}
+
+func convertToPublicDraft(d database.ConversationDraft, counterpartId pgtype.UUID) PublicDraft {
+	return PublicDraft{
+		UserID:       counterpartId,
+		MssgBody:     d.MssgBody,
+		AttachMssgID: d.AttachMssgID,
+		Version:      d.Version,
+		UpdatedAt:    &d.UpdatedAt,
+	}
+}
+
+// publishDraft syncs a draft to the other devices of its owner
+func publishDraft(ctx context.Context, hub *realtime.Hub, d database.ConversationDraft, counterpartId pgtype.UUID) {
+	err := hub.Publish(ctx, []int32{d.OwnerPvtID}, draftEvent, convertToPublicDraft(d, counterpartId))
+	if err != nil {
+		slog.Warn("could not publish draft", "owner_pvt_id", d.OwnerPvtID, "error", err)
+	}
+}
+
+// clearDraftOnSend empties the draft of a conversation a message was just
+// sent to, the cleared draft is nil when there was nothing to clear
+func clearDraftOnSend(ctx context.Context, queries *database.Queries, fromPvtId, toPvtId int32) (*database.ConversationDraft, error) {
+	draft, err := queries.ClearDraft(ctx, database.ClearDraftParams{
+		UpdatedAt:        time.Now().UTC(),
+		OwnerPvtID:       fromPvtId,
+		CounterpartPvtID: toPvtId,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &draft, nil
+}
+
+func handleGetDraft(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	draft, err := queries.GetDraft(r.Context(), database.GetDraftParams{
+		OwnerPvtID:       user.PvtID,
+		CounterpartPvtID: counterpart.PvtID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondSuccess(w, http.StatusOK, PublicDraft{UserID: counterpart.UserID})
+		return
+	} else if err != nil {
+		slog.Error("could not fetch draft", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToPublicDraft(draft, counterpart.UserID))
+}
+
+type saveDraftData struct {
+	MssgBody     string `json:"mssg_body"      validate:"maxgraphemes=4096"`
+	AttachMssgId int64  `json:"attach_mssg_id" validate:"omitempty,min=1"`
+	Version      int32  `json:"version"        validate:"min=0"`
+}
+
+// handleSaveDraft writes the draft if nobody changed it since the version
+// the client sent, version 0 creates the first draft of a conversation
+func handleSaveDraft(w http.ResponseWriter, r *http.Request) {
+	data := saveDraftData{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&data)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+	// a draft is held to the same rules as the message it turns into
+	data.MssgBody = richtext.Clean(data.MssgBody)
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(data)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	attachMssgId := pgtype.Int8{Int64: data.AttachMssgId, Valid: data.AttachMssgId != 0}
+	if attachMssgId.Valid {
+		m, err := queries.GetMessageById(r.Context(), data.AttachMssgId)
+		if err != nil || (m.FromPvtID != user.PvtID && m.ToPvtID != user.PvtID) {
+			render.RespondFailure(w, http.StatusBadRequest, messageNotFoundError)
+			return
+		}
+	}
+	slog.Info("saving draft", "user_id", user.UserID, "counterpart_id", counterpart.UserID, "version", data.Version)
+
+	now := time.Now().UTC()
+	var draft database.ConversationDraft
+	if data.Version == 0 {
+		draft, err = queries.CreateDraft(r.Context(), database.CreateDraftParams{
+			OwnerPvtID:       user.PvtID,
+			CounterpartPvtID: counterpart.PvtID,
+			MssgBody:         data.MssgBody,
+			AttachMssgID:     attachMssgId,
+			UpdatedAt:        now,
+		})
+	} else {
+		draft, err = queries.UpdateDraft(r.Context(), database.UpdateDraftParams{
+			MssgBody:         data.MssgBody,
+			AttachMssgID:     attachMssgId,
+			UpdatedAt:        now,
+			OwnerPvtID:       user.PvtID,
+			CounterpartPvtID: counterpart.PvtID,
+			Version:          data.Version,
+		})
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondFailure(w, http.StatusConflict, draftConflictError)
+		return
+	} else if err != nil {
+		slog.Error("could not save draft", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	publishDraft(r.Context(), apiCfg.Hub, draft, counterpart.UserID)
+	render.RespondSuccess(w, http.StatusOK, convertToPublicDraft(draft, counterpart.UserID))
+}
+
+// handleDeleteDraft clears the draft, with a version query parameter only if
+// the draft is still at that version
+func handleDeleteDraft(w http.ResponseWriter, r *http.Request) {
+	version, err := parseInt32Query(r, "version", 0)
+	if err != nil || version < 0 {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid draft version")
+		return
+	}
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	slog.Info("clearing draft", "user_id", user.UserID, "counterpart_id", counterpart.UserID)
+
+	draft, err := queries.ClearDraft(r.Context(), database.ClearDraftParams{
+		UpdatedAt:        time.Now().UTC(),
+		OwnerPvtID:       user.PvtID,
+		CounterpartPvtID: counterpart.PvtID,
+		Version:          pgtype.Int4{Int32: version, Valid: version != 0},
+	})
+	if err == nil {
+		publishDraft(r.Context(), apiCfg.Hub, draft, counterpart.UserID)
+		render.RespondSuccess(w, http.StatusOK, convertToPublicDraft(draft, counterpart.UserID))
+		return
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("could not clear draft", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+
+	// nothing was cleared, either it was empty already or the version is old
+	current, err := queries.GetDraft(r.Context(), database.GetDraftParams{
+		OwnerPvtID:       user.PvtID,
+		CounterpartPvtID: counterpart.PvtID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondSuccess(w, http.StatusOK, PublicDraft{UserID: counterpart.UserID})
+		return
+	} else if err != nil {
+		slog.Error("could not fetch draft", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	if version != 0 && current.Version != version {
+		render.RespondFailure(w, http.StatusConflict, draftConflictError)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToPublicDraft(current, counterpart.UserID))
+}
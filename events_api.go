@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	eventKeepAlive = 25 * time.Second
+	// stored events are loaded by the other instances as soon as the
+	// notification arrives, they are kept a little longer than that
+	storedEventRetention             = time.Minute
+	storedEventPrunerInterval        = time.Minute
+	storedEventPrunerBatchSize int32 = 1000
+)
+
+// handleEventStream holds a server-sent events connection open for the user.
+// Open connections also count towards the user's presence.
+func handleEventStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		render.RespondFailure(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("opening event stream", "user_id", user.UserID, "user_name", user.Username)
+
+	apiCfg := apiconf.GetConfig(r)
+	sub := apiCfg.Hub.Subscribe(user.PvtID)
+	defer apiCfg.Hub.Unsubscribe(sub)
+	apiCfg.Presence.Connect(user.PvtID)
+	defer apiCfg.Presence.Disconnect(user.PvtID)
+
+	w.Header().Set("content-type", "text/event-stream")
+	w.Header().Set("cache-control", "no-cache")
+	w.Header().Set("connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			slog.Info("closing event stream", "user_id", user.UserID, "user_name", user.Username)
+			return
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case ev, ok := <-sub.Events:
+			// the hub stops on shutdown
+			if !ok {
+				slog.Info("closing event stream", "user_id", user.UserID, "user_name", user.Username)
+				return
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, ev.Data)
+		}
+		if err != nil {
+			slog.Warn("could not write to event stream", "user_id", user.UserID, "error", err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func EventRouter() *chi.Mux {
+	router := chi.NewMux()
+
+	router.Get("/", handleEventStream)
+
+	return router
+}
+
+// runStoredEventPruner drops events too large for a notification once the
+// other instances had their chance to load them
+func runStoredEventPruner(ctx context.Context, apiCfg apiconf.ApiConfig) {
+	runBatches(ctx, "stored event pruner", storedEventPrunerInterval, storedEventPrunerBatchSize, func(ctx context.Context) (int32, error) {
+		queries := database.New(apiCfg.ConnPool)
+		deleted, err := queries.DeleteOldRealtimeEvents(ctx, database.DeleteOldRealtimeEventsParams{
+			CreatedAt: time.Now().UTC().Add(-storedEventRetention),
+			Limit:     storedEventPrunerBatchSize,
+		})
+		return int32(deleted), err
+	})
+}
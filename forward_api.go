@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	errForwardDisappearing = errors.New("messages from conversations with disappearing messages cannot be forwarded")
+	errForwardReaction     = errors.New("reactions cannot be forwarded")
+	errForwardPoll         = errors.New("polls cannot be forwarded")
+)
+
+type forwardMessagesData struct {
+	MssgIds   []int64       `json:"mssg_ids"    validate:"required,min=1,max=50,dive,min=1"`
+	ToUserIds []pgtype.UUID `json:"to_user_ids" validate:"required,min=1,max=20,dive,required"`
+}
+
+// forwardSource is a message that is copied to every recipient
+type forwardSource struct {
+	message    database.GetMessageByIdRow
+	attachment *database.Attachment
+	location   *database.MessageLocation
+	contact    *database.MessageContact
+}
+
+// getForwardSource loads a message the caller takes part in and refuses the
+// ones that are meant to disappear
+func getForwardSource(ctx context.Context, queries *database.Queries, caller database.User, mssgId int64) (forwardSource, error) {
+	m, err := queries.GetMessageById(ctx, mssgId)
+	if err != nil {
+		return forwardSource{}, err
+	}
+	if m.FromPvtID != caller.PvtID && m.ToPvtID != caller.PvtID {
+		return forwardSource{}, pgx.ErrNoRows
+	}
+	if m.MssgType == database.MessageTypeReaction {
+		return forwardSource{}, errForwardReaction
+	}
+	// votes belong to the conversation the poll was asked in
+	if m.MssgType == database.MessageTypePoll {
+		return forwardSource{}, errForwardPoll
+	}
+	if m.ExpiresAt.Valid || m.PurgedAt.Valid {
+		return forwardSource{}, errForwardDisappearing
+	}
+	_, err = queries.GetConversationTTL(ctx, database.GetConversationTTLParams{
+		UserPvtID:  m.FromPvtID,
+		OtherPvtID: m.ToPvtID,
+	})
+	if err == nil {
+		return forwardSource{}, errForwardDisappearing
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return forwardSource{}, err
+	}
+
+	source := forwardSource{message: m}
+	switch m.MssgType {
+	case database.MessageTypeAttachment:
+		attachment, err := queries.GetMessageAttachment(ctx, pgtype.Int8{Int64: m.MssgID, Valid: true})
+		if err != nil {
+			return forwardSource{}, err
+		}
+		source.attachment = &attachment
+	case database.MessageTypeLocation:
+		location, err := queries.GetMessageLocation(ctx, m.MssgID)
+		if err != nil {
+			return forwardSource{}, err
+		}
+		source.location = &location
+	case database.MessageTypeContact:
+		contact, err := queries.GetMessageContact(ctx, m.MssgID)
+		if err != nil {
+			return forwardSource{}, err
+		}
+		source.contact = &contact
+	}
+	return source, nil
+}
+
+// shareAttachment gives the forwarding user their own attachment for the
+// file, an attachment belongs to exactly one message. The blob itself is
+// shared with the original and not copied.
+func shareAttachment(ctx context.Context, queries *database.Queries, a database.Attachment, ownerPvtId int32) (pgtype.UUID, error) {
+	attachmentId, err := newUUID()
+	if err != nil {
+		return pgtype.UUID{}, err
+	}
+	_, err = queries.CreateAttachment(ctx, database.CreateAttachmentParams{
+		AttachmentID: attachmentId,
+		OwnerPvtID:   ownerPvtId,
+		FileName:     a.FileName,
+		MimeType:     a.MimeType,
+		SizeBytes:    a.SizeBytes,
+		Checksum:     a.Checksum,
+		Width:        a.Width,
+		Height:       a.Height,
+		CreatedAt:    time.Now().UTC(),
+		BlobID:       a.BlobID,
+	})
+	return attachmentId, err
+}
+
+// handleForwardMessages copies messages to other conversations. The copies
+// are new messages of the caller that remember who wrote the original.
+func handleForwardMessages(w http.ResponseWriter, r *http.Request) {
+	data := forwardMessagesData{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&data)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(data)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("forwarding messages", "user_id", user.UserID, "mssg_ids", data.MssgIds)
+
+	queries := database.New(apiCfg.ConnPool)
+	sources := make([]forwardSource, 0, len(data.MssgIds))
+	for _, mssgId := range data.MssgIds {
+		source, err := getForwardSource(r.Context(), queries, user, mssgId)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+			return
+		case errors.Is(err, errForwardDisappearing), errors.Is(err, errForwardReaction), errors.Is(err, errForwardPoll):
+			render.RespondFailure(w, http.StatusForbidden, err.Error())
+			return
+		case err != nil:
+			slog.Error("could not fetch message to forward", "mssg_id", mssgId, "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+			return
+		}
+		sources = append(sources, source)
+	}
+	recipients := make([]database.User, 0, len(data.ToUserIds))
+	for _, userId := range data.ToUserIds {
+		toUser, err := queries.GetUserByUuid(r.Context(), userId)
+		if err == nil {
+			err = checkCanMessage(r.Context(), queries, user, toUser)
+		}
+		if err != nil {
+			respondCannotMessage(w, err)
+			return
+		}
+		for _, source := range sources {
+			if source.contact == nil {
+				continue
+			}
+			err = checkCanShareContact(r.Context(), queries, toUser.PvtID, source.contact.ContactPvtID)
+			if errors.Is(err, errContactNotFound) {
+				render.RespondFailure(w, http.StatusBadRequest, errContactNotFound.Error())
+				return
+			} else if err != nil {
+				slog.Error("could not check forwarded contact", "mssg_id", source.message.MssgID, "error", err)
+				render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+				return
+			}
+		}
+		recipients = append(recipients, toUser)
+	}
+
+	c, err := apiCfg.ConnPool.Acquire(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	defer c.Release()
+	tx, err := c.Begin(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	defer tx.Rollback(r.Context())
+
+	txQueries := queries.WithTx(tx)
+	forwarded := make([]sentMessage, 0, len(sources)*len(recipients))
+	for _, toUser := range recipients {
+		for _, source := range sources {
+			original := source.message
+			author := original.ForwardedFromPvtID
+			if !author.Valid {
+				author = pgtype.Int4{Int32: original.FromPvtID, Valid: true}
+			}
+			m := newMessage{
+				FromPvtID:          user.PvtID,
+				ToPvtID:            toUser.PvtID,
+				MssgType:           database.MessageTypeNormal,
+				MssgBody:           original.MssgBody,
+				Format:             original.MssgFormat,
+				ForwardedFromPvtID: author,
+			}
+			if source.attachment != nil {
+				m.MssgType = database.MessageTypeAttachment
+				m.AttachmentID, err = shareAttachment(r.Context(), txQueries, *source.attachment, user.PvtID)
+			}
+			// a forwarded location is where the original was last, it does
+			// not move with the original sender
+			if source.location != nil {
+				m.MssgType = database.MessageTypeLocation
+				m.Location = &newLocation{
+					Latitude:       source.location.Latitude,
+					Longitude:      source.location.Longitude,
+					AccuracyMeters: source.location.AccuracyMeters,
+				}
+			}
+			if source.contact != nil {
+				m.MssgType = database.MessageTypeContact
+				m.ContactPvtID = pgtype.Int4{Int32: source.contact.ContactPvtID, Valid: true}
+			}
+			var content database.MessagePublic
+			if err == nil {
+				content, err = insertMessage(r.Context(), txQueries, m)
+			}
+			if err == nil {
+				forwarded = append(forwarded, sentMessage{content: content, fromPvtId: user.PvtID, toPvtId: toUser.PvtID})
+				continue
+			}
+			slog.Error("could not forward message", "mssg_id", original.MssgID, "error", err)
+			render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
+			return
+		}
+	}
+	err = queueMessageWebhooks(r.Context(), txQueries, forwarded)
+	if err != nil {
+		slog.Error("could not queue message webhooks", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
+		return
+	}
+	err = tx.Commit(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
+		return
+	}
+
+	public := make([]PublicMessage, 0, len(forwarded))
+	for _, m := range forwarded {
+		publishMessage(r.Context(), apiCfg.Hub, m.content, m.fromPvtId, m.toPvtId)
+		public = append(public, convertToPublicMessage(m.content, user))
+	}
+	notifyRecipients(r.Context(), apiCfg, forwarded)
+	render.RespondSuccess(w, http.StatusOK, public)
+}
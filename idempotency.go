@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	// idempotencyRetention is how long a retry returns the original message,
+	// after that the key can be used again
+	idempotencyRetention             = 24 * time.Hour
+	idempotencyPrunerInterval        = time.Hour
+	idempotencyPrunerBatchSize int32 = 1000
+)
+
+var (
+	errIdempotencyKeyReused  = errors.New("idempotency key was used for a different message")
+	errIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// hashCreateMessage fingerprints a request so a reused key with a different
+// message is rejected instead of replayed
+func hashCreateMessage(data createMessageData) (string, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// claimIdempotencyKey reserves the key for this request inside the message
+// transaction. A retry of a committed request gets the original key back,
+// a retry racing the original waits on the unique key until it commits.
+func claimIdempotencyKey(ctx context.Context, queries *database.Queries, fromPvtId int32, key, requestHash string) (*database.MessageIdempotencyKey, error) {
+	now := time.Now().UTC()
+	_, err := queries.ClaimIdempotencyKey(ctx, database.ClaimIdempotencyKeyParams{
+		FromPvtID:      fromPvtId,
+		IdempotencyKey: key,
+		RequestHash:    requestHash,
+		CreatedAt:      now,
+		ExpiredBefore:  now.Add(-idempotencyRetention),
+	})
+	if err == nil {
+		return nil, nil
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	original, err := queries.GetIdempotencyKey(ctx, database.GetIdempotencyKeyParams{
+		FromPvtID:      fromPvtId,
+		IdempotencyKey: key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if original.RequestHash != requestHash {
+		return nil, errIdempotencyKeyReused
+	}
+	if !original.MssgID.Valid && !original.ScheduleID.Valid {
+		return nil, errIdempotencyInProgress
+	}
+	return &original, nil
+}
+
+// recordIdempotencyResult points the key at what the request created, it
+// is a no-op for requests without a key
+func recordIdempotencyResult(ctx context.Context, queries *database.Queries, fromPvtId int32, key string, mssgId, scheduleId pgtype.Int8) error {
+	if key == "" {
+		return nil
+	}
+	return queries.SetIdempotencyResult(ctx, database.SetIdempotencyResultParams{
+		MssgID:         mssgId,
+		ScheduleID:     scheduleId,
+		FromPvtID:      fromPvtId,
+		IdempotencyKey: key,
+	})
+}
+
+// respondReplay sends back what the original request created
+func respondReplay(w http.ResponseWriter, r *http.Request, queries *database.Queries, original database.MessageIdempotencyKey, fromUser, toUser database.User) {
+	slog.Info("replaying idempotent request", "user_id", fromUser.UserID, "idempotency_key", original.IdempotencyKey)
+	w.Header().Set(idempotencyReplayedHeader, "true")
+	if original.ScheduleID.Valid {
+		sm, err := queries.GetScheduledMessage(r.Context(), database.GetScheduledMessageParams{
+			ScheduleID: original.ScheduleID.Int64,
+			FromPvtID:  fromUser.PvtID,
+		})
+		if err != nil {
+			slog.Error("could not fetch original scheduled message", "schedule_id", original.ScheduleID.Int64, "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+			return
+		}
+		render.RespondSuccess(w, http.StatusAccepted, convertToPublicScheduled(sm, toUser.UserID))
+		return
+	}
+	mssgContent, err := queries.GetMessageByIdPublic(r.Context(), original.MssgID.Int64)
+	if err != nil {
+		slog.Error("could not fetch original message", "mssg_id", original.MssgID.Int64, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToPublicMessage(mssgContent, fromUser))
+}
+
+// runIdempotencyPruner drops keys past their retention window
+func runIdempotencyPruner(ctx context.Context, apiCfg apiconf.ApiConfig) {
+	runBatches(ctx, "idempotency pruner", idempotencyPrunerInterval, idempotencyPrunerBatchSize, func(ctx context.Context) (int32, error) {
+		queries := database.New(apiCfg.ConnPool)
+		deleted, err := queries.DeleteExpiredIdempotencyKeys(ctx, database.DeleteExpiredIdempotencyKeysParams{
+			CreatedAt: time.Now().UTC().Add(-idempotencyRetention),
+			Limit:     idempotencyPrunerBatchSize,
+		})
+		return int32(deleted), err
+	})
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// newUUID generates a random (version 4) UUID
+func newUUID() (pgtype.UUID, error) {
+	id := pgtype.UUID{Valid: true}
+	_, err := rand.Read(id.Bytes[:])
+	if err != nil {
+		return pgtype.UUID{}, err
+	}
+	id.Bytes[6] = (id.Bytes[6] & 0x0f) | 0x40
+	id.Bytes[8] = (id.Bytes[8] & 0x3f) | 0x80
+	return id, nil
+}
+
+func uuidString(id pgtype.UUID) string {
+	b := id.Bytes
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -2,12 +2,24 @@ package apiconf
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/Suryarpan/chat-api/internal/blobstore"
+	"github.com/Suryarpan/chat-api/internal/linkpreview"
+	"github.com/Suryarpan/chat-api/internal/presence"
+	"github.com/Suryarpan/chat-api/internal/realtime"
+	"github.com/Suryarpan/chat-api/internal/richtext"
+	"github.com/Suryarpan/chat-api/internal/webhook"
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,9 +27,19 @@ type ctxKeyApiConfig string
 
 const chatApiConfigKey ctxKeyApiConfig = "CHAT_API_DB_URL"
 
+// copyEnumTypes are the enums written with COPY, which only speaks the
+// binary format and needs to know their codecs up front
+var copyEnumTypes = []string{"message_format", "message_status", "message_type"}
+
 type ApiConfig struct {
-	ConnPool *pgxpool.Pool
-	Validate *validator.Validate
+	ConnPool  *pgxpool.Pool
+	Validate  *validator.Validate
+	Localizer *Localizer
+	Blobs     blobstore.BlobStore
+	Hub       *realtime.Hub
+	Presence  *presence.Registry
+	Previews  linkpreview.Fetcher
+	Webhooks  *webhook.Sender
 }
 
 func SetupPool() (*pgxpool.Pool, error) {
@@ -31,6 +53,16 @@ func SetupPool() (*pgxpool.Pool, error) {
 	dbConfig.MaxConnIdleTime = time.Minute * 5
 	dbConfig.HealthCheckPeriod = time.Minute
 	dbConfig.ConnConfig.ConnectTimeout = time.Second * 10
+	dbConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		for _, name := range copyEnumTypes {
+			t, err := conn.LoadType(ctx, name)
+			if err != nil {
+				return err
+			}
+			conn.TypeMap().RegisterType(t)
+		}
+		return nil
+	}
 
 	connPool, err := pgxpool.NewWithConfig(context.Background(), dbConfig)
 	if err != nil {
@@ -48,14 +80,116 @@ func setupValidator() *validator.Validate {
 	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
 		return field.Tag.Get("json")
 	})
+	validate.RegisterValidation("maxgraphemes", maxGraphemes)
+	validate.RegisterValidation("printtext", printText)
 	return validate
 }
 
-func ApiConfigure(connPool *pgxpool.Pool) func(http.Handler) http.Handler {
-	apiCfg := ApiConfig{
-		ConnPool: connPool,
-		Validate: setupValidator(),
+// printText refuses control characters and broken UTF-8, with the multiline
+// parameter line breaks are still allowed
+func printText(fl validator.FieldLevel) bool {
+	text := fl.Field().String()
+	if !utf8.ValidString(text) {
+		return false
+	}
+	multiline := fl.Param() == "multiline"
+	for _, r := range text {
+		if r == '\n' && multiline {
+			continue
+		}
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// graphemeLimits holds the maxgraphemes parameters parsed by CheckTags, it
+// is only written before the server starts
+var graphemeLimits = map[string]int{}
+
+// maxGraphemes limits text by the characters users see rather than bytes or
+// code points, so an emoji sequence counts once
+func maxGraphemes(fl validator.FieldLevel) bool {
+	limit, ok := graphemeLimits[fl.Param()]
+	if !ok {
+		var err error
+		limit, err = parseGraphemeLimit(fl.Param())
+		if err != nil {
+			slog.Error("bad maxgraphemes parameter", "param", fl.Param(), "error", err)
+			return false
+		}
+	}
+	return richtext.Length(fl.Field().String()) <= limit
+}
+
+func parseGraphemeLimit(param string) (int, error) {
+	limit, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, err
+	}
+	if limit < 0 {
+		return 0, fmt.Errorf("negative limit %d", limit)
+	}
+	return limit, nil
+}
+
+// CheckTags goes through the validation tags of the given request types and
+// their nested types, so a bad parameter of the custom tags stops the api
+// at startup instead of failing requests
+func CheckTags(samples ...any) error {
+	seen := map[reflect.Type]bool{}
+	for _, sample := range samples {
+		err := checkTypeTags(reflect.TypeOf(sample), seen)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkTypeTags(t reflect.Type, seen map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+	seen[t] = true
+	for i := range t.NumField() {
+		field := t.Field(i)
+		for _, option := range strings.FieldsFunc(field.Tag.Get("validate"), func(r rune) bool { return r == ',' || r == '|' }) {
+			name, param, _ := strings.Cut(option, "=")
+			switch name {
+			case "maxgraphemes":
+				limit, err := parseGraphemeLimit(param)
+				if err != nil {
+					return fmt.Errorf("%s.%s: bad maxgraphemes parameter %q: %w", t.Name(), field.Name, param, err)
+				}
+				graphemeLimits[param] = limit
+			case "printtext":
+				if param != "" && param != "multiline" {
+					return fmt.Errorf("%s.%s: bad printtext parameter %q", t.Name(), field.Name, param)
+				}
+			}
+		}
+		err := checkTypeTags(field.Type, seen)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApiConfigure shares the given services with every request, the validator
+// and its translations are set up here
+func ApiConfigure(apiCfg ApiConfig) func(http.Handler) http.Handler {
+	apiCfg.Validate = setupValidator()
+	localizer, err := setupLocalizer(apiCfg.Validate)
+	if err != nil {
+		panic(fmt.Sprintf("Error: could not setup validation translations: %v", err))
 	}
+	apiCfg.Localizer = localizer
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := context.WithValue(r.Context(), chatApiConfigKey, apiCfg)
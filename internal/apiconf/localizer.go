@@ -0,0 +1,124 @@
+package apiconf
+
+import (
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/locales/ja"
+	"github.com/go-playground/locales/pt"
+	"github.com/go-playground/locales/ru"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	es_translations "github.com/go-playground/validator/v10/translations/es"
+	fr_translations "github.com/go-playground/validator/v10/translations/fr"
+	ja_translations "github.com/go-playground/validator/v10/translations/ja"
+	pt_translations "github.com/go-playground/validator/v10/translations/pt"
+	ru_translations "github.com/go-playground/validator/v10/translations/ru"
+	zh_translations "github.com/go-playground/validator/v10/translations/zh"
+	"golang.org/x/text/language"
+)
+
+type supportedLocale struct {
+	tag      language.Tag
+	locale   locales.Translator
+	register func(*validator.Validate, ut.Translator) error
+	// custom has the messages of the tags this api adds to the validator
+	custom map[string]string
+}
+
+// the first locale is used whenever nothing better matches
+var supportedLocales = []supportedLocale{
+	{language.English, en.New(), en_translations.RegisterDefaultTranslations, map[string]string{
+		"maxgraphemes": "{0} must be at most {1} characters long",
+		"printtext":    "{0} must not contain control characters",
+	}},
+	{language.Spanish, es.New(), es_translations.RegisterDefaultTranslations, map[string]string{
+		"maxgraphemes": "{0} debe tener como máximo {1} caracteres",
+		"printtext":    "{0} no debe contener caracteres de control",
+	}},
+	{language.French, fr.New(), fr_translations.RegisterDefaultTranslations, map[string]string{
+		"maxgraphemes": "{0} doit contenir au maximum {1} caractères",
+		"printtext":    "{0} ne doit pas contenir de caractères de contrôle",
+	}},
+	{language.Japanese, ja.New(), ja_translations.RegisterDefaultTranslations, map[string]string{
+		"maxgraphemes": "{0}は最大{1}文字でなければなりません",
+		"printtext":    "{0}に制御文字を含めることはできません",
+	}},
+	{language.Portuguese, pt.New(), pt_translations.RegisterDefaultTranslations, map[string]string{
+		"maxgraphemes": "{0} deve ter no máximo {1} caracteres",
+		"printtext":    "{0} não deve conter caracteres de controle",
+	}},
+	{language.Russian, ru.New(), ru_translations.RegisterDefaultTranslations, map[string]string{
+		"maxgraphemes": "{0} должен содержать максимум {1} символов",
+		"printtext":    "{0} не должен содержать управляющие символы",
+	}},
+	{language.Chinese, zh.New(), zh_translations.RegisterDefaultTranslations, map[string]string{
+		"maxgraphemes": "{0}长度不能超过{1}个字符",
+		"printtext":    "{0}不能包含控制字符",
+	}},
+}
+
+// Localizer picks the closest supported translation for a user's locale
+type Localizer struct {
+	uni     *ut.UniversalTranslator
+	matcher language.Matcher
+}
+
+func setupLocalizer(validate *validator.Validate) (*Localizer, error) {
+	tags := make([]language.Tag, 0, len(supportedLocales))
+	translators := make([]locales.Translator, 0, len(supportedLocales))
+	for _, l := range supportedLocales {
+		tags = append(tags, l.tag)
+		translators = append(translators, l.locale)
+	}
+	uni := ut.New(translators[0], translators...)
+	for _, l := range supportedLocales {
+		trans, _ := uni.GetTranslator(l.locale.Locale())
+		err := l.register(validate, trans)
+		if err != nil {
+			return nil, err
+		}
+		err = registerCustomTranslations(validate, trans, l.custom)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &Localizer{
+		uni:     uni,
+		matcher: language.NewMatcher(tags),
+	}, nil
+}
+
+// Translator accepts a single language tag or a full Accept-Language value
+func (l *Localizer) Translator(preference string) ut.Translator {
+	prefs, _, err := language.ParseAcceptLanguage(preference)
+	if err != nil || len(prefs) == 0 {
+		return l.uni.GetFallback()
+	}
+	_, idx, conf := l.matcher.Match(prefs...)
+	if conf == language.No {
+		return l.uni.GetFallback()
+	}
+	trans, _ := l.uni.GetTranslator(supportedLocales[idx].locale.Locale())
+	return trans
+}
+
+// registerCustomTranslations covers the tags this api adds to the validator,
+// the field name and the tag parameter can be used in the messages
+func registerCustomTranslations(validate *validator.Validate, trans ut.Translator, messages map[string]string) error {
+	for tag, text := range messages {
+		err := validate.RegisterTranslation(tag, trans, func(ut ut.Translator) error {
+			return ut.Add(tag, text, false)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T(tag, fe.Field(), fe.Param())
+			return t
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -47,6 +47,7 @@ func Authentication(next http.Handler) http.Handler {
 			render.RespondFailure(w, http.StatusInternalServerError, "could not login at this time")
 			return
 		}
+		apiCfg.Presence.Touch(user.PvtID)
 		ctx := context.WithValue(r.Context(), ctxUserDataKey, user)
 		rr := r.WithContext(ctx)
 		next.ServeHTTP(w, rr)
@@ -61,3 +62,9 @@ func GetUserData(r *http.Request) database.User {
 	}
 	return data
 }
+
+// LookupUserData is GetUserData for routes where authentication is optional
+func LookupUserData(r *http.Request) (database.User, bool) {
+	data, ok := r.Context().Value(ctxUserDataKey).(database.User)
+	return data, ok
+}
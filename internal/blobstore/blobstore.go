@@ -0,0 +1,37 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps opaque binary objects addressed by a slash separated key
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+func SetupStore() (BlobStore, error) {
+	kind, ok := os.LookupEnv("CHAT_API_BLOB_STORE")
+	if !ok {
+		kind = "local"
+	}
+	switch kind {
+	case "local":
+		dir, ok := os.LookupEnv("CHAT_API_BLOB_DIR")
+		if !ok {
+			dir = "_data/blobs"
+		}
+		return NewLocalStore(dir)
+	case "s3":
+		return NewS3Store(context.Background(), S3ConfigFromEnv())
+	default:
+		return nil, fmt.Errorf("could not understand blob store: %s", kind)
+	}
+}
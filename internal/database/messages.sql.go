@@ -12,20 +12,104 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const claimExpiredMessages = `-- name: ClaimExpiredMessages :many
+SELECT mssg_id, from_pvt_id, to_pvt_id
+FROM message_meta
+WHERE expires_at <= $1 AND purged_at IS NULL
+ORDER BY expires_at
+LIMIT $2
+FOR UPDATE SKIP LOCKED
+`
+
+type ClaimExpiredMessagesParams struct {
+	ExpiresAt pgtype.Timestamp `json:"expires_at"`
+	Limit     int32            `json:"limit"`
+}
+
+type ClaimExpiredMessagesRow struct {
+	MssgID    int64 `json:"mssg_id"`
+	FromPvtID int32 `json:"from_pvt_id"`
+	ToPvtID   int32 `json:"to_pvt_id"`
+}
+
+func (q *Queries) ClaimExpiredMessages(ctx context.Context, arg ClaimExpiredMessagesParams) ([]ClaimExpiredMessagesRow, error) {
+	rows, err := q.db.Query(ctx, claimExpiredMessages, arg.ExpiresAt, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimExpiredMessagesRow
+	for rows.Next() {
+		var i ClaimExpiredMessagesRow
+		if err := rows.Scan(&i.MssgID, &i.FromPvtID, &i.ToPvtID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const clearConversationTTL = `-- name: ClearConversationTTL :execrows
+DELETE FROM conversation_ttl
+WHERE low_pvt_id = least($1::integer, $2::integer)
+    AND high_pvt_id = greatest($1::integer, $2::integer)
+`
+
+type ClearConversationTTLParams struct {
+	UserPvtID  int32 `json:"user_pvt_id"`
+	OtherPvtID int32 `json:"other_pvt_id"`
+}
+
+func (q *Queries) ClearConversationTTL(ctx context.Context, arg ClearConversationTTLParams) (int64, error) {
+	result, err := q.db.Exec(ctx, clearConversationTTL, arg.UserPvtID, arg.OtherPvtID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+type CopyMessageMetaParams struct {
+	MssgID     int64            `json:"mssg_id"`
+	FromPvtID  int32            `json:"from_pvt_id"`
+	ToPvtID    int32            `json:"to_pvt_id"`
+	MssgStatus MessageStatus    `json:"mssg_status"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	ExpiresAt  pgtype.Timestamp `json:"expires_at"`
+}
+
+type CopyMessageTextsParams struct {
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
+}
+
+type CopyMessageTypesParams struct {
+	MssgID       int64       `json:"mssg_id"`
+	MssgType     MessageType `json:"mssg_type"`
+	AttachMssgID pgtype.Int8 `json:"attach_mssg_id"`
+}
+
 const createMessage = `-- name: CreateMessage :one
 INSERT INTO message_meta (
-    from_pvt_id, to_pvt_id, mssg_status, created_at, updated_at
+    from_pvt_id, to_pvt_id, mssg_status, created_at, updated_at, expires_at, forwarded_from_pvt_id
 ) VALUES (
-    $1, $2, $3, $4, $5
-) RETURNING mssg_id, from_pvt_id, to_pvt_id, mssg_status, created_at, updated_at
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING mssg_id, from_pvt_id, to_pvt_id, mssg_status, created_at, updated_at, expires_at, purged_at, forwarded_from_pvt_id
 `
 
 type CreateMessageParams struct {
-	FromPvtID  int32         `json:"from_pvt_id"`
-	ToPvtID    int32         `json:"to_pvt_id"`
-	MssgStatus MessageStatus `json:"mssg_status"`
-	CreatedAt  time.Time     `json:"created_at"`
-	UpdatedAt  time.Time     `json:"updated_at"`
+	FromPvtID          int32            `json:"from_pvt_id"`
+	ToPvtID            int32            `json:"to_pvt_id"`
+	MssgStatus         MessageStatus    `json:"mssg_status"`
+	CreatedAt          time.Time        `json:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at"`
+	ExpiresAt          pgtype.Timestamp `json:"expires_at"`
+	ForwardedFromPvtID pgtype.Int4      `json:"forwarded_from_pvt_id"`
 }
 
 func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (MessageMetum, error) {
@@ -35,6 +119,8 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		arg.MssgStatus,
 		arg.CreatedAt,
 		arg.UpdatedAt,
+		arg.ExpiresAt,
+		arg.ForwardedFromPvtID,
 	)
 	var i MessageMetum
 	err := row.Scan(
@@ -44,27 +130,42 @@ func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (M
 		&i.MssgStatus,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.PurgedAt,
+		&i.ForwardedFromPvtID,
 	)
 	return i, err
 }
 
 const createMessageText = `-- name: CreateMessageText :one
 INSERT INTO message_text (
-    mssg_id, mssg_body
+    mssg_id, mssg_body, mssg_format, mssg_html
 ) VALUES (
-    $1, $2
-) RETURNING mssg_id, mssg_body
+    $1, $2, $3, $4
+) RETURNING mssg_id, mssg_body, mssg_format, mssg_html
 `
 
 type CreateMessageTextParams struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 func (q *Queries) CreateMessageText(ctx context.Context, arg CreateMessageTextParams) (MessageText, error) {
-	row := q.db.QueryRow(ctx, createMessageText, arg.MssgID, arg.MssgBody)
+	row := q.db.QueryRow(ctx, createMessageText,
+		arg.MssgID,
+		arg.MssgBody,
+		arg.MssgFormat,
+		arg.MssgHtml,
+	)
 	var i MessageText
-	err := row.Scan(&i.MssgID, &i.MssgBody)
+	err := row.Scan(
+		&i.MssgID,
+		&i.MssgBody,
+		&i.MssgFormat,
+		&i.MssgHtml,
+	)
 	return i, err
 }
 
@@ -89,8 +190,33 @@ func (q *Queries) CreateMessageType(ctx context.Context, arg CreateMessageTypePa
 	return i, err
 }
 
+const getConversationTTL = `-- name: GetConversationTTL :one
+SELECT low_pvt_id, high_pvt_id, ttl_seconds, set_by_pvt_id, updated_at
+FROM conversation_ttl
+WHERE low_pvt_id = least($1::integer, $2::integer)
+    AND high_pvt_id = greatest($1::integer, $2::integer)
+`
+
+type GetConversationTTLParams struct {
+	UserPvtID  int32 `json:"user_pvt_id"`
+	OtherPvtID int32 `json:"other_pvt_id"`
+}
+
+func (q *Queries) GetConversationTTL(ctx context.Context, arg GetConversationTTLParams) (ConversationTtl, error) {
+	row := q.db.QueryRow(ctx, getConversationTTL, arg.UserPvtID, arg.OtherPvtID)
+	var i ConversationTtl
+	err := row.Scan(
+		&i.LowPvtID,
+		&i.HighPvtID,
+		&i.TtlSeconds,
+		&i.SetByPvtID,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getMessageById = `-- name: GetMessageById :one
-SELECT mm.mssg_id, mm.from_pvt_id, mm.to_pvt_id, mm.mssg_status, mm.created_at, mm.updated_at, mtm.mssg_type, mtm.attach_mssg_id, mt.mssg_body
+SELECT mm.mssg_id, mm.from_pvt_id, mm.to_pvt_id, mm.mssg_status, mm.created_at, mm.updated_at, mm.expires_at, mm.purged_at, mm.forwarded_from_pvt_id, mtm.mssg_type, mtm.attach_mssg_id, mt.mssg_body, mt.mssg_format
 FROM message_meta mm
 JOIN message_type_meta mtm ON mtm.mssg_id = mm.mssg_id
 JOIN message_text mt ON mt.mssg_id = mm.mssg_id
@@ -98,15 +224,19 @@ WHERE mm.mssg_id = $1
 `
 
 type GetMessageByIdRow struct {
-	MssgID       int64         `json:"mssg_id"`
-	FromPvtID    int32         `json:"from_pvt_id"`
-	ToPvtID      int32         `json:"to_pvt_id"`
-	MssgStatus   MessageStatus `json:"mssg_status"`
-	CreatedAt    time.Time     `json:"created_at"`
-	UpdatedAt    time.Time     `json:"updated_at"`
-	MssgType     MessageType   `json:"mssg_type"`
-	AttachMssgID pgtype.Int8   `json:"attach_mssg_id"`
-	MssgBody     string        `json:"mssg_body"`
+	MssgID             int64            `json:"mssg_id"`
+	FromPvtID          int32            `json:"from_pvt_id"`
+	ToPvtID            int32            `json:"to_pvt_id"`
+	MssgStatus         MessageStatus    `json:"mssg_status"`
+	CreatedAt          time.Time        `json:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at"`
+	ExpiresAt          pgtype.Timestamp `json:"expires_at"`
+	PurgedAt           pgtype.Timestamp `json:"purged_at"`
+	ForwardedFromPvtID pgtype.Int4      `json:"forwarded_from_pvt_id"`
+	MssgType           MessageType      `json:"mssg_type"`
+	AttachMssgID       pgtype.Int8      `json:"attach_mssg_id"`
+	MssgBody           string           `json:"mssg_body"`
+	MssgFormat         MessageFormat    `json:"mssg_format"`
 }
 
 func (q *Queries) GetMessageById(ctx context.Context, mssgID int64) (GetMessageByIdRow, error) {
@@ -119,38 +249,26 @@ func (q *Queries) GetMessageById(ctx context.Context, mssgID int64) (GetMessageB
 		&i.MssgStatus,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.PurgedAt,
+		&i.ForwardedFromPvtID,
 		&i.MssgType,
 		&i.AttachMssgID,
 		&i.MssgBody,
+		&i.MssgFormat,
 	)
 	return i, err
 }
 
 const getMessageByIdPublic = `-- name: GetMessageByIdPublic :one
-SELECT mm.mssg_id, fu.user_id as from_user_id, tu.user_id as to_user_id, mm.mssg_status, mm.created_at, mm.updated_at, mtm.mssg_type, mtm.attach_mssg_id, mt.mssg_body
-FROM message_meta mm
-JOIN message_type_meta mtm ON mtm.mssg_id = mm.mssg_id
-JOIN message_text mt ON mt.mssg_id = mm.mssg_id
-JOIN users fu ON fu.pvt_id = mm.from_pvt_id
-JOIN users tu ON tu.pvt_id = mm.to_pvt_id
-WHERE mm.mssg_id = $1
+SELECT mssg_id, from_user_id, to_user_id, mssg_status, created_at, updated_at, mssg_type, attach_mssg_id, mssg_body, mssg_format, mssg_html, read_receipts, attachment_id, file_name, mime_type, size_bytes, checksum, width, height, expires_at, forwarded_from_user_id, pinned, mentions, poll, preview_url, preview_title, preview_description, preview_image_url, preview_site_name, location_latitude, location_longitude, location_accuracy_meters, location_live_until, location_updated_at, contact_user_id, contact_username, contact_display_name, from_pvt_id, to_pvt_id
+FROM message_public
+WHERE mssg_id = $1
 `
 
-type GetMessageByIdPublicRow struct {
-	MssgID       int64         `json:"mssg_id"`
-	FromUserID   pgtype.UUID   `json:"from_user_id"`
-	ToUserID     pgtype.UUID   `json:"to_user_id"`
-	MssgStatus   MessageStatus `json:"mssg_status"`
-	CreatedAt    time.Time     `json:"created_at"`
-	UpdatedAt    time.Time     `json:"updated_at"`
-	MssgType     MessageType   `json:"mssg_type"`
-	AttachMssgID pgtype.Int8   `json:"attach_mssg_id"`
-	MssgBody     string        `json:"mssg_body"`
-}
-
-func (q *Queries) GetMessageByIdPublic(ctx context.Context, mssgID int64) (GetMessageByIdPublicRow, error) {
+func (q *Queries) GetMessageByIdPublic(ctx context.Context, mssgID int64) (MessagePublic, error) {
 	row := q.db.QueryRow(ctx, getMessageByIdPublic, mssgID)
-	var i GetMessageByIdPublicRow
+	var i MessagePublic
 	err := row.Scan(
 		&i.MssgID,
 		&i.FromUserID,
@@ -161,6 +279,438 @@ func (q *Queries) GetMessageByIdPublic(ctx context.Context, mssgID int64) (GetMe
 		&i.MssgType,
 		&i.AttachMssgID,
 		&i.MssgBody,
+		&i.MssgFormat,
+		&i.MssgHtml,
+		&i.ReadReceipts,
+		&i.AttachmentID,
+		&i.FileName,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.Checksum,
+		&i.Width,
+		&i.Height,
+		&i.ExpiresAt,
+		&i.ForwardedFromUserID,
+		&i.Pinned,
+		&i.Mentions,
+		&i.Poll,
+		&i.PreviewUrl,
+		&i.PreviewTitle,
+		&i.PreviewDescription,
+		&i.PreviewImageUrl,
+		&i.PreviewSiteName,
+		&i.LocationLatitude,
+		&i.LocationLongitude,
+		&i.LocationAccuracyMeters,
+		&i.LocationLiveUntil,
+		&i.LocationUpdatedAt,
+		&i.ContactUserID,
+		&i.ContactUsername,
+		&i.ContactDisplayName,
+		&i.FromPvtID,
+		&i.ToPvtID,
+	)
+	return i, err
+}
+
+const getUnreadSummary = `-- name: GetUnreadSummary :one
+SELECT coalesce(sum(unread_count), 0)::bigint as total_unread,
+    count(*) FILTER (WHERE unread_count > 0) as unread_conversations
+FROM conversation_unread
+WHERE owner_pvt_id = $1
+`
+
+type GetUnreadSummaryRow struct {
+	TotalUnread         int64 `json:"total_unread"`
+	UnreadConversations int64 `json:"unread_conversations"`
+}
+
+func (q *Queries) GetUnreadSummary(ctx context.Context, ownerPvtID int32) (GetUnreadSummaryRow, error) {
+	row := q.db.QueryRow(ctx, getUnreadSummary, ownerPvtID)
+	var i GetUnreadSummaryRow
+	err := row.Scan(&i.TotalUnread, &i.UnreadConversations)
+	return i, err
+}
+
+const listConversationMessageIds = `-- name: ListConversationMessageIds :many
+SELECT mm.mssg_id, c.counterpart_pvt_id::integer as counterpart_pvt_id
+FROM unnest($1::BIGINT[], $2::INTEGER[]) AS c(mssg_id, counterpart_pvt_id)
+JOIN message_meta mm ON mm.mssg_id = c.mssg_id
+WHERE (mm.from_pvt_id = $3 AND mm.to_pvt_id = c.counterpart_pvt_id)
+    OR (mm.from_pvt_id = c.counterpart_pvt_id AND mm.to_pvt_id = $3)
+`
+
+type ListConversationMessageIdsParams struct {
+	MssgIds           []int64 `json:"mssg_ids"`
+	CounterpartPvtIds []int32 `json:"counterpart_pvt_ids"`
+	UserPvtID         int32   `json:"user_pvt_id"`
+}
+
+type ListConversationMessageIdsRow struct {
+	MssgID           int64 `json:"mssg_id"`
+	CounterpartPvtID int32 `json:"counterpart_pvt_id"`
+}
+
+func (q *Queries) ListConversationMessageIds(ctx context.Context, arg ListConversationMessageIdsParams) ([]ListConversationMessageIdsRow, error) {
+	rows, err := q.db.Query(ctx, listConversationMessageIds, arg.MssgIds, arg.CounterpartPvtIds, arg.UserPvtID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListConversationMessageIdsRow
+	for rows.Next() {
+		var i ListConversationMessageIdsRow
+		if err := rows.Scan(&i.MssgID, &i.CounterpartPvtID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listMessagesByIdsPublic = `-- name: ListMessagesByIdsPublic :many
+SELECT mssg_id, from_user_id, to_user_id, mssg_status, created_at, updated_at, mssg_type, attach_mssg_id, mssg_body, mssg_format, mssg_html, read_receipts, attachment_id, file_name, mime_type, size_bytes, checksum, width, height, expires_at, forwarded_from_user_id, pinned, mentions, poll, preview_url, preview_title, preview_description, preview_image_url, preview_site_name, location_latitude, location_longitude, location_accuracy_meters, location_live_until, location_updated_at, contact_user_id, contact_username, contact_display_name, from_pvt_id, to_pvt_id
+FROM message_public
+WHERE mssg_id = ANY($1::bigint[])
+ORDER BY mssg_id
+`
+
+func (q *Queries) ListMessagesByIdsPublic(ctx context.Context, mssgIds []int64) ([]MessagePublic, error) {
+	rows, err := q.db.Query(ctx, listMessagesByIdsPublic, mssgIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MessagePublic
+	for rows.Next() {
+		var i MessagePublic
+		if err := rows.Scan(
+			&i.MssgID,
+			&i.FromUserID,
+			&i.ToUserID,
+			&i.MssgStatus,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.MssgType,
+			&i.AttachMssgID,
+			&i.MssgBody,
+			&i.MssgFormat,
+			&i.MssgHtml,
+			&i.ReadReceipts,
+			&i.AttachmentID,
+			&i.FileName,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.Checksum,
+			&i.Width,
+			&i.Height,
+			&i.ExpiresAt,
+			&i.ForwardedFromUserID,
+			&i.Pinned,
+			&i.Mentions,
+			&i.Poll,
+			&i.PreviewUrl,
+			&i.PreviewTitle,
+			&i.PreviewDescription,
+			&i.PreviewImageUrl,
+			&i.PreviewSiteName,
+			&i.LocationLatitude,
+			&i.LocationLongitude,
+			&i.LocationAccuracyMeters,
+			&i.LocationLiveUntil,
+			&i.LocationUpdatedAt,
+			&i.ContactUserID,
+			&i.ContactUsername,
+			&i.ContactDisplayName,
+			&i.FromPvtID,
+			&i.ToPvtID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnreadConversations = `-- name: ListUnreadConversations :many
+SELECT u.user_id, cu.unread_count
+FROM conversation_unread cu
+JOIN users u ON u.pvt_id = cu.counterpart_pvt_id
+LEFT JOIN conversation_settings cs ON cs.owner_pvt_id = cu.owner_pvt_id AND cs.counterpart_pvt_id = cu.counterpart_pvt_id
+WHERE cu.owner_pvt_id = $1 AND cu.unread_count > 0 AND cs.archived_at IS NULL
+ORDER BY cu.unread_count DESC, cu.counterpart_pvt_id
+LIMIT $2 OFFSET $3
+`
+
+type ListUnreadConversationsParams struct {
+	OwnerPvtID int32 `json:"owner_pvt_id"`
+	Limit      int32 `json:"limit"`
+	Offset     int32 `json:"offset"`
+}
+
+type ListUnreadConversationsRow struct {
+	UserID      pgtype.UUID `json:"user_id"`
+	UnreadCount int32       `json:"unread_count"`
+}
+
+func (q *Queries) ListUnreadConversations(ctx context.Context, arg ListUnreadConversationsParams) ([]ListUnreadConversationsRow, error) {
+	rows, err := q.db.Query(ctx, listUnreadConversations, arg.OwnerPvtID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnreadConversationsRow
+	for rows.Next() {
+		var i ListUnreadConversationsRow
+		if err := rows.Scan(&i.UserID, &i.UnreadCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markConversationRead = `-- name: MarkConversationRead :many
+UPDATE message_meta
+SET mssg_status = 'read', updated_at = $1
+WHERE to_pvt_id = $2 AND from_pvt_id = $3 AND mssg_status <> 'read'
+RETURNING mssg_id
+`
+
+type MarkConversationReadParams struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	ToPvtID   int32     `json:"to_pvt_id"`
+	FromPvtID int32     `json:"from_pvt_id"`
+}
+
+func (q *Queries) MarkConversationRead(ctx context.Context, arg MarkConversationReadParams) ([]int64, error) {
+	rows, err := q.db.Query(ctx, markConversationRead, arg.UpdatedAt, arg.ToPvtID, arg.FromPvtID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var mssg_id int64
+		if err := rows.Scan(&mssg_id); err != nil {
+			return nil, err
+		}
+		items = append(items, mssg_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markMessageRead = `-- name: MarkMessageRead :one
+UPDATE message_meta
+SET mssg_status = 'read', updated_at = $1
+WHERE mssg_id = $2 AND to_pvt_id = $3 AND mssg_status <> 'read'
+RETURNING mssg_id, from_pvt_id, to_pvt_id, mssg_status, created_at, updated_at, expires_at, purged_at, forwarded_from_pvt_id
+`
+
+type MarkMessageReadParams struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	MssgID    int64     `json:"mssg_id"`
+	ToPvtID   int32     `json:"to_pvt_id"`
+}
+
+func (q *Queries) MarkMessageRead(ctx context.Context, arg MarkMessageReadParams) (MessageMetum, error) {
+	row := q.db.QueryRow(ctx, markMessageRead, arg.UpdatedAt, arg.MssgID, arg.ToPvtID)
+	var i MessageMetum
+	err := row.Scan(
+		&i.MssgID,
+		&i.FromPvtID,
+		&i.ToPvtID,
+		&i.MssgStatus,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.PurgedAt,
+		&i.ForwardedFromPvtID,
+	)
+	return i, err
+}
+
+const markMessagesPurged = `-- name: MarkMessagesPurged :exec
+UPDATE message_meta
+SET purged_at = $1
+WHERE mssg_id = ANY($2::bigint[])
+`
+
+type MarkMessagesPurgedParams struct {
+	PurgedAt pgtype.Timestamp `json:"purged_at"`
+	MssgIds  []int64          `json:"mssg_ids"`
+}
+
+func (q *Queries) MarkMessagesPurged(ctx context.Context, arg MarkMessagesPurgedParams) error {
+	_, err := q.db.Exec(ctx, markMessagesPurged, arg.PurgedAt, arg.MssgIds)
+	return err
+}
+
+const purgeMessageBodies = `-- name: PurgeMessageBodies :exec
+UPDATE message_text
+SET mssg_body = '', mssg_html = NULL
+WHERE mssg_id = ANY($1::bigint[])
+`
+
+func (q *Queries) PurgeMessageBodies(ctx context.Context, mssgIds []int64) error {
+	_, err := q.db.Exec(ctx, purgeMessageBodies, mssgIds)
+	return err
+}
+
+const reserveMessageIds = `-- name: ReserveMessageIds :many
+SELECT nextval(pg_get_serial_sequence('message_meta', 'mssg_id'))::BIGINT AS mssg_id
+FROM generate_series(1, $1::INTEGER)
+`
+
+func (q *Queries) ReserveMessageIds(ctx context.Context, count int32) ([]int64, error) {
+	rows, err := q.db.Query(ctx, reserveMessageIds, count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var mssg_id int64
+		if err := rows.Scan(&mssg_id); err != nil {
+			return nil, err
+		}
+		items = append(items, mssg_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchMessages = `-- name: SearchMessages :many
+SELECT mm.mssg_id, fu.user_id as from_user_id, tu.user_id as to_user_id, mm.created_at, mtm.mssg_type,
+    ts_headline('simple', translate(mt.mssg_body, E'\x02\x03', ''), websearch_to_tsquery('simple', $1),
+        E'StartSel=\x02, StopSel=\x03, MaxFragments=2, MaxWords=24, MinWords=8, FragmentDelimiter=" ... "')::text as snippet
+FROM message_text mt
+JOIN message_meta mm ON mm.mssg_id = mt.mssg_id
+JOIN message_type_meta mtm ON mtm.mssg_id = mm.mssg_id
+JOIN users fu ON fu.pvt_id = mm.from_pvt_id
+JOIN users tu ON tu.pvt_id = mm.to_pvt_id
+WHERE to_tsvector('simple', mt.mssg_body) @@ websearch_to_tsquery('simple', $1)
+    AND (mm.from_pvt_id = $2 OR mm.to_pvt_id = $2)
+    AND (mm.expires_at IS NULL OR mm.expires_at > $3)
+    AND ($4::integer IS NULL
+        OR (mm.from_pvt_id = $2 AND mm.to_pvt_id = $4)
+        OR (mm.from_pvt_id = $4 AND mm.to_pvt_id = $2))
+    AND ($5::timestamp IS NULL OR mm.created_at >= $5)
+    AND ($6::timestamp IS NULL OR mm.created_at < $6)
+    AND ($7::message_type IS NULL OR mtm.mssg_type = $7)
+    AND ($8::timestamp IS NULL
+        OR (mm.created_at, mm.mssg_id) < ($8, $9::bigint))
+ORDER BY mm.created_at DESC, mm.mssg_id DESC
+LIMIT $10
+`
+
+type SearchMessagesParams struct {
+	Query            string           `json:"query"`
+	CallerPvtID      int32            `json:"caller_pvt_id"`
+	Now              pgtype.Timestamp `json:"now"`
+	CounterpartPvtID pgtype.Int4      `json:"counterpart_pvt_id"`
+	SentAfter        pgtype.Timestamp `json:"sent_after"`
+	SentBefore       pgtype.Timestamp `json:"sent_before"`
+	MssgType         NullMessageType  `json:"mssg_type"`
+	CursorCreatedAt  pgtype.Timestamp `json:"cursor_created_at"`
+	CursorMssgID     pgtype.Int8      `json:"cursor_mssg_id"`
+	PageLimit        int32            `json:"page_limit"`
+}
+
+type SearchMessagesRow struct {
+	MssgID     int64       `json:"mssg_id"`
+	FromUserID pgtype.UUID `json:"from_user_id"`
+	ToUserID   pgtype.UUID `json:"to_user_id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	MssgType   MessageType `json:"mssg_type"`
+	Snippet    string      `json:"snippet"`
+}
+
+func (q *Queries) SearchMessages(ctx context.Context, arg SearchMessagesParams) ([]SearchMessagesRow, error) {
+	rows, err := q.db.Query(ctx, searchMessages,
+		arg.Query,
+		arg.CallerPvtID,
+		arg.Now,
+		arg.CounterpartPvtID,
+		arg.SentAfter,
+		arg.SentBefore,
+		arg.MssgType,
+		arg.CursorCreatedAt,
+		arg.CursorMssgID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchMessagesRow
+	for rows.Next() {
+		var i SearchMessagesRow
+		if err := rows.Scan(
+			&i.MssgID,
+			&i.FromUserID,
+			&i.ToUserID,
+			&i.CreatedAt,
+			&i.MssgType,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setConversationTTL = `-- name: SetConversationTTL :one
+INSERT INTO conversation_ttl (
+    low_pvt_id, high_pvt_id, ttl_seconds, set_by_pvt_id, updated_at
+) VALUES (
+    least($1::integer, $2::integer),
+    greatest($1::integer, $2::integer),
+    $3, $1, $4
+) ON CONFLICT (low_pvt_id, high_pvt_id) DO UPDATE
+SET ttl_seconds = EXCLUDED.ttl_seconds, set_by_pvt_id = EXCLUDED.set_by_pvt_id, updated_at = EXCLUDED.updated_at
+RETURNING low_pvt_id, high_pvt_id, ttl_seconds, set_by_pvt_id, updated_at
+`
+
+type SetConversationTTLParams struct {
+	UserPvtID  int32     `json:"user_pvt_id"`
+	OtherPvtID int32     `json:"other_pvt_id"`
+	TtlSeconds int32     `json:"ttl_seconds"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (q *Queries) SetConversationTTL(ctx context.Context, arg SetConversationTTLParams) (ConversationTtl, error) {
+	row := q.db.QueryRow(ctx, setConversationTTL,
+		arg.UserPvtID,
+		arg.OtherPvtID,
+		arg.TtlSeconds,
+		arg.UpdatedAt,
+	)
+	var i ConversationTtl
+	err := row.Scan(
+		&i.LowPvtID,
+		&i.HighPvtID,
+		&i.TtlSeconds,
+		&i.SetByPvtID,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
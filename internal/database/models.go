@@ -12,6 +12,134 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type FriendRequestStatus string
+
+const (
+	FriendRequestStatusPending   FriendRequestStatus = "pending"
+	FriendRequestStatusAccepted  FriendRequestStatus = "accepted"
+	FriendRequestStatusDeclined  FriendRequestStatus = "declined"
+	FriendRequestStatusCancelled FriendRequestStatus = "cancelled"
+)
+
+func (e *FriendRequestStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = FriendRequestStatus(s)
+	case string:
+		*e = FriendRequestStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for FriendRequestStatus: %T", src)
+	}
+	return nil
+}
+
+type NullFriendRequestStatus struct {
+	FriendRequestStatus FriendRequestStatus `json:"friend_request_status"`
+	Valid               bool                `json:"valid"` // Valid is true if FriendRequestStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullFriendRequestStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.FriendRequestStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.FriendRequestStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullFriendRequestStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.FriendRequestStatus), nil
+}
+
+type MessageFormat string
+
+const (
+	MessageFormatPlain    MessageFormat = "plain"
+	MessageFormatMarkdown MessageFormat = "markdown"
+)
+
+func (e *MessageFormat) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = MessageFormat(s)
+	case string:
+		*e = MessageFormat(s)
+	default:
+		return fmt.Errorf("unsupported scan type for MessageFormat: %T", src)
+	}
+	return nil
+}
+
+type NullMessageFormat struct {
+	MessageFormat MessageFormat `json:"message_format"`
+	Valid         bool          `json:"valid"` // Valid is true if MessageFormat is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullMessageFormat) Scan(value interface{}) error {
+	if value == nil {
+		ns.MessageFormat, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.MessageFormat.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullMessageFormat) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.MessageFormat), nil
+}
+
+type MessagePrivacy string
+
+const (
+	MessagePrivacyEveryone MessagePrivacy = "everyone"
+	MessagePrivacyContacts MessagePrivacy = "contacts"
+)
+
+func (e *MessagePrivacy) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = MessagePrivacy(s)
+	case string:
+		*e = MessagePrivacy(s)
+	default:
+		return fmt.Errorf("unsupported scan type for MessagePrivacy: %T", src)
+	}
+	return nil
+}
+
+type NullMessagePrivacy struct {
+	MessagePrivacy MessagePrivacy `json:"message_privacy"`
+	Valid          bool           `json:"valid"` // Valid is true if MessagePrivacy is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullMessagePrivacy) Scan(value interface{}) error {
+	if value == nil {
+		ns.MessagePrivacy, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.MessagePrivacy.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullMessagePrivacy) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.MessagePrivacy), nil
+}
+
 type MessageStatus string
 
 const (
@@ -58,9 +186,13 @@ func (ns NullMessageStatus) Value() (driver.Value, error) {
 type MessageType string
 
 const (
-	MessageTypeNormal   MessageType = "normal"
-	MessageTypeReply    MessageType = "reply"
-	MessageTypeReaction MessageType = "reaction"
+	MessageTypeNormal     MessageType = "normal"
+	MessageTypeReply      MessageType = "reply"
+	MessageTypeReaction   MessageType = "reaction"
+	MessageTypeAttachment MessageType = "attachment"
+	MessageTypePoll       MessageType = "poll"
+	MessageTypeLocation   MessageType = "location"
+	MessageTypeContact    MessageType = "contact"
 )
 
 func (e *MessageType) Scan(src interface{}) error {
@@ -98,68 +230,373 @@ func (ns NullMessageType) Value() (driver.Value, error) {
 	return string(ns.MessageType), nil
 }
 
+type ScheduledStatus string
+
+const (
+	ScheduledStatusPending ScheduledStatus = "pending"
+	ScheduledStatusSent    ScheduledStatus = "sent"
+	ScheduledStatusFailed  ScheduledStatus = "failed"
+)
+
+func (e *ScheduledStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ScheduledStatus(s)
+	case string:
+		*e = ScheduledStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ScheduledStatus: %T", src)
+	}
+	return nil
+}
+
+type NullScheduledStatus struct {
+	ScheduledStatus ScheduledStatus `json:"scheduled_status"`
+	Valid           bool            `json:"valid"` // Valid is true if ScheduledStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullScheduledStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ScheduledStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ScheduledStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullScheduledStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ScheduledStatus), nil
+}
+
+type Visibility string
+
+const (
+	VisibilityEveryone Visibility = "everyone"
+	VisibilityContacts Visibility = "contacts"
+	VisibilityNobody   Visibility = "nobody"
+)
+
+func (e *Visibility) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = Visibility(s)
+	case string:
+		*e = Visibility(s)
+	default:
+		return fmt.Errorf("unsupported scan type for Visibility: %T", src)
+	}
+	return nil
+}
+
+type NullVisibility struct {
+	Visibility Visibility `json:"visibility"`
+	Valid      bool       `json:"valid"` // Valid is true if Visibility is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullVisibility) Scan(value interface{}) error {
+	if value == nil {
+		ns.Visibility, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.Visibility.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullVisibility) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.Visibility), nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusSucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+)
+
+func (e *WebhookDeliveryStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveryStatus(s)
+	case string:
+		*e = WebhookDeliveryStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveryStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookDeliveryStatus struct {
+	WebhookDeliveryStatus WebhookDeliveryStatus `json:"webhook_delivery_status"`
+	Valid                 bool                  `json:"valid"` // Valid is true if WebhookDeliveryStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookDeliveryStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookDeliveryStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookDeliveryStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookDeliveryStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookDeliveryStatus), nil
+}
+
+type Attachment struct {
+	AttachmentID pgtype.UUID `json:"attachment_id"`
+	OwnerPvtID   int32       `json:"owner_pvt_id"`
+	MssgID       pgtype.Int8 `json:"mssg_id"`
+	FileName     string      `json:"file_name"`
+	MimeType     string      `json:"mime_type"`
+	SizeBytes    int64       `json:"size_bytes"`
+	Checksum     string      `json:"checksum"`
+	Width        pgtype.Int4 `json:"width"`
+	Height       pgtype.Int4 `json:"height"`
+	CreatedAt    time.Time   `json:"created_at"`
+	BlobID       pgtype.UUID `json:"blob_id"`
+}
+
+type Contact struct {
+	OwnerPvtID   int32     `json:"owner_pvt_id"`
+	ContactPvtID int32     `json:"contact_pvt_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ConversationDraft struct {
+	OwnerPvtID       int32       `json:"owner_pvt_id"`
+	CounterpartPvtID int32       `json:"counterpart_pvt_id"`
+	MssgBody         string      `json:"mssg_body"`
+	AttachMssgID     pgtype.Int8 `json:"attach_mssg_id"`
+	Version          int32       `json:"version"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+type ConversationSetting struct {
+	OwnerPvtID       int32            `json:"owner_pvt_id"`
+	CounterpartPvtID int32            `json:"counterpart_pvt_id"`
+	Muted            bool             `json:"muted"`
+	MutedUntil       pgtype.Timestamp `json:"muted_until"`
+	ArchivedAt       pgtype.Timestamp `json:"archived_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+}
+
+type ConversationTtl struct {
+	LowPvtID   int32     `json:"low_pvt_id"`
+	HighPvtID  int32     `json:"high_pvt_id"`
+	TtlSeconds int32     `json:"ttl_seconds"`
+	SetByPvtID int32     `json:"set_by_pvt_id"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type ConversationUnread struct {
+	OwnerPvtID       int32 `json:"owner_pvt_id"`
+	CounterpartPvtID int32 `json:"counterpart_pvt_id"`
+	UnreadCount      int32 `json:"unread_count"`
+}
+
+type FriendRequest struct {
+	RequestID     int64               `json:"request_id"`
+	FromPvtID     int32               `json:"from_pvt_id"`
+	ToPvtID       int32               `json:"to_pvt_id"`
+	RequestStatus FriendRequestStatus `json:"request_status"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+}
+
+type LinkPreview struct {
+	Url         string    `json:"url"`
+	Ok          bool      `json:"ok"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	ImageUrl    string    `json:"image_url"`
+	SiteName    string    `json:"site_name"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+type MessageContact struct {
+	MssgID       int64 `json:"mssg_id"`
+	ContactPvtID int32 `json:"contact_pvt_id"`
+}
+
+type MessageIdempotencyKey struct {
+	FromPvtID      int32       `json:"from_pvt_id"`
+	IdempotencyKey string      `json:"idempotency_key"`
+	RequestHash    string      `json:"request_hash"`
+	MssgID         pgtype.Int8 `json:"mssg_id"`
+	ScheduleID     pgtype.Int8 `json:"schedule_id"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+type MessageLinkPreview struct {
+	MssgID      int64            `json:"mssg_id"`
+	Url         string           `json:"url"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UnfurledAt  pgtype.Timestamp `json:"unfurled_at"`
+	LeasedUntil pgtype.Timestamp `json:"leased_until"`
+}
+
+type MessageLocation struct {
+	MssgID         int64            `json:"mssg_id"`
+	Latitude       float64          `json:"latitude"`
+	Longitude      float64          `json:"longitude"`
+	AccuracyMeters pgtype.Float8    `json:"accuracy_meters"`
+	LiveUntil      pgtype.Timestamp `json:"live_until"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+type MessageMention struct {
+	MssgID         int64 `json:"mssg_id"`
+	MentionedPvtID int32 `json:"mentioned_pvt_id"`
+	StartOffset    int32 `json:"start_offset"`
+	Length         int32 `json:"length"`
+}
+
 type MessageMetum struct {
-	MssgID     int64         `json:"mssg_id"`
-	FromPvtID  int32         `json:"from_pvt_id"`
-	ToPvtID    int32         `json:"to_pvt_id"`
-	MssgStatus MessageStatus `json:"mssg_status"`
-	CreatedAt  time.Time     `json:"created_at"`
-	UpdatedAt  time.Time     `json:"updated_at"`
+	MssgID             int64            `json:"mssg_id"`
+	FromPvtID          int32            `json:"from_pvt_id"`
+	ToPvtID            int32            `json:"to_pvt_id"`
+	MssgStatus         MessageStatus    `json:"mssg_status"`
+	CreatedAt          time.Time        `json:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at"`
+	ExpiresAt          pgtype.Timestamp `json:"expires_at"`
+	PurgedAt           pgtype.Timestamp `json:"purged_at"`
+	ForwardedFromPvtID pgtype.Int4      `json:"forwarded_from_pvt_id"`
+}
+
+type MessagePublic struct {
+	MssgID                 int64            `json:"mssg_id"`
+	FromUserID             pgtype.UUID      `json:"from_user_id"`
+	ToUserID               pgtype.UUID      `json:"to_user_id"`
+	MssgStatus             MessageStatus    `json:"mssg_status"`
+	CreatedAt              time.Time        `json:"created_at"`
+	UpdatedAt              time.Time        `json:"updated_at"`
+	MssgType               MessageType      `json:"mssg_type"`
+	AttachMssgID           pgtype.Int8      `json:"attach_mssg_id"`
+	MssgBody               string           `json:"mssg_body"`
+	MssgFormat             MessageFormat    `json:"mssg_format"`
+	MssgHtml               pgtype.Text      `json:"mssg_html"`
+	ReadReceipts           bool             `json:"read_receipts"`
+	AttachmentID           pgtype.UUID      `json:"attachment_id"`
+	FileName               pgtype.Text      `json:"file_name"`
+	MimeType               pgtype.Text      `json:"mime_type"`
+	SizeBytes              pgtype.Int8      `json:"size_bytes"`
+	Checksum               pgtype.Text      `json:"checksum"`
+	Width                  pgtype.Int4      `json:"width"`
+	Height                 pgtype.Int4      `json:"height"`
+	ExpiresAt              pgtype.Timestamp `json:"expires_at"`
+	ForwardedFromUserID    pgtype.UUID      `json:"forwarded_from_user_id"`
+	Pinned                 bool             `json:"pinned"`
+	Mentions               []byte           `json:"mentions"`
+	Poll                   []byte           `json:"poll"`
+	PreviewUrl             pgtype.Text      `json:"preview_url"`
+	PreviewTitle           pgtype.Text      `json:"preview_title"`
+	PreviewDescription     pgtype.Text      `json:"preview_description"`
+	PreviewImageUrl        pgtype.Text      `json:"preview_image_url"`
+	PreviewSiteName        pgtype.Text      `json:"preview_site_name"`
+	LocationLatitude       pgtype.Float8    `json:"location_latitude"`
+	LocationLongitude      pgtype.Float8    `json:"location_longitude"`
+	LocationAccuracyMeters pgtype.Float8    `json:"location_accuracy_meters"`
+	LocationLiveUntil      pgtype.Timestamp `json:"location_live_until"`
+	LocationUpdatedAt      pgtype.Timestamp `json:"location_updated_at"`
+	ContactUserID          pgtype.UUID      `json:"contact_user_id"`
+	ContactUsername        pgtype.Text      `json:"contact_username"`
+	ContactDisplayName     pgtype.Text      `json:"contact_display_name"`
+	FromPvtID              int32            `json:"from_pvt_id"`
+	ToPvtID                int32            `json:"to_pvt_id"`
 }
 
 type MessageText struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 type MessageText0 struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 type MessageText1 struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 type MessageText2 struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 type MessageText3 struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 type MessageText4 struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 type MessageText5 struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 type MessageText6 struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 type MessageText7 struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 type MessageText8 struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 type MessageText9 struct {
-	MssgID   int64  `json:"mssg_id"`
-	MssgBody string `json:"mssg_body"`
+	MssgID     int64         `json:"mssg_id"`
+	MssgBody   string        `json:"mssg_body"`
+	MssgFormat MessageFormat `json:"mssg_format"`
+	MssgHtml   pgtype.Text   `json:"mssg_html"`
 }
 
 type MessageTypeMetum struct {
@@ -168,14 +605,119 @@ type MessageTypeMetum struct {
 	AttachMssgID pgtype.Int8 `json:"attach_mssg_id"`
 }
 
+type Poll struct {
+	MssgID         int64            `json:"mssg_id"`
+	MultipleChoice bool             `json:"multiple_choice"`
+	Anonymous      bool             `json:"anonymous"`
+	ClosesAt       pgtype.Timestamp `json:"closes_at"`
+}
+
+type PollOption struct {
+	MssgID     int64  `json:"mssg_id"`
+	OptionID   int32  `json:"option_id"`
+	OptionText string `json:"option_text"`
+}
+
+type PollVote struct {
+	MssgID     int64     `json:"mssg_id"`
+	OptionID   int32     `json:"option_id"`
+	VoterPvtID int32     `json:"voter_pvt_id"`
+	VotedAt    time.Time `json:"voted_at"`
+}
+
+type PresenceConnection struct {
+	InstanceID  pgtype.UUID `json:"instance_id"`
+	PvtID       int32       `json:"pvt_id"`
+	Connections int32       `json:"connections"`
+	HeartbeatAt time.Time   `json:"heartbeat_at"`
+}
+
+type RealtimeEvent struct {
+	EventID   int64     `json:"event_id"`
+	Payload   string    `json:"payload"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScheduledMessage struct {
+	ScheduleID    int64           `json:"schedule_id"`
+	FromPvtID     int32           `json:"from_pvt_id"`
+	ToPvtID       int32           `json:"to_pvt_id"`
+	MssgType      MessageType     `json:"mssg_type"`
+	AttachMssgID  pgtype.Int8     `json:"attach_mssg_id"`
+	AttachmentID  pgtype.UUID     `json:"attachment_id"`
+	MssgBody      string          `json:"mssg_body"`
+	SendAt        time.Time       `json:"send_at"`
+	Status        ScheduledStatus `json:"status"`
+	MssgID        pgtype.Int8     `json:"mssg_id"`
+	FailureReason pgtype.Text     `json:"failure_reason"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	TtlSeconds    pgtype.Int4     `json:"ttl_seconds"`
+	MssgFormat    MessageFormat   `json:"mssg_format"`
+}
+
 type User struct {
-	PvtID        int32            `json:"pvt_id"`
-	UserID       pgtype.UUID      `json:"user_id"`
-	Username     string           `json:"username"`
-	DisplayName  string           `json:"display_name"`
-	Password     []byte           `json:"password"`
-	PasswordSalt []byte           `json:"password_salt"`
-	CreatedAt    time.Time        `json:"created_at"`
-	UpdatedAt    time.Time        `json:"updated_at"`
-	LastLoggedIn pgtype.Timestamp `json:"last_logged_in"`
+	PvtID           int32            `json:"pvt_id"`
+	UserID          pgtype.UUID      `json:"user_id"`
+	Username        string           `json:"username"`
+	DisplayName     string           `json:"display_name"`
+	Password        []byte           `json:"password"`
+	PasswordSalt    []byte           `json:"password_salt"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+	LastLoggedIn    pgtype.Timestamp `json:"last_logged_in"`
+	AvatarID        pgtype.UUID      `json:"avatar_id"`
+	Bio             string           `json:"bio"`
+	StatusText      string           `json:"status_text"`
+	StatusExpiresAt pgtype.Timestamp `json:"status_expires_at"`
+	Timezone        string           `json:"timezone"`
+	Locale          string           `json:"locale"`
+}
+
+type UserBlock struct {
+	BlockerPvtID int32     `json:"blocker_pvt_id"`
+	BlockedPvtID int32     `json:"blocked_pvt_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type UserPresence struct {
+	PvtID        int32     `json:"pvt_id"`
+	LastActiveAt time.Time `json:"last_active_at"`
+}
+
+type UserSetting struct {
+	PvtID              int32          `json:"pvt_id"`
+	AcceptMessagesFrom MessagePrivacy `json:"accept_messages_from"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	LastSeenVisibility Visibility     `json:"last_seen_visibility"`
+	SendReadReceipts   bool           `json:"send_read_receipts"`
+}
+
+type WebhookDelivery struct {
+	DeliveryID     int64                 `json:"delivery_id"`
+	WebhookID      pgtype.UUID           `json:"webhook_id"`
+	Event          string                `json:"event"`
+	Payload        []byte                `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int32                 `json:"attempts"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at"`
+	LastAttemptAt  pgtype.Timestamp      `json:"last_attempt_at"`
+	ResponseStatus pgtype.Int4           `json:"response_status"`
+	LastError      string                `json:"last_error"`
+	CreatedAt      time.Time             `json:"created_at"`
+	DeliveredAt    pgtype.Timestamp      `json:"delivered_at"`
+	MssgID         pgtype.Int8           `json:"mssg_id"`
+}
+
+type WebhookSubscription struct {
+	WebhookID           pgtype.UUID      `json:"webhook_id"`
+	OwnerPvtID          int32            `json:"owner_pvt_id"`
+	Url                 string           `json:"url"`
+	Secret              string           `json:"secret"`
+	Events              []string         `json:"events"`
+	Enabled             bool             `json:"enabled"`
+	ConsecutiveFailures int32            `json:"consecutive_failures"`
+	DisabledAt          pgtype.Timestamp `json:"disabled_at"`
+	CreatedAt           time.Time        `json:"created_at"`
+	UpdatedAt           time.Time        `json:"updated_at"`
 }
@@ -12,12 +12,31 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const blockUser = `-- name: BlockUser :exec
+INSERT INTO user_blocks (
+    blocker_pvt_id, blocked_pvt_id, created_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT DO NOTHING
+`
+
+type BlockUserParams struct {
+	BlockerPvtID int32     `json:"blocker_pvt_id"`
+	BlockedPvtID int32     `json:"blocked_pvt_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (q *Queries) BlockUser(ctx context.Context, arg BlockUserParams) error {
+	_, err := q.db.Exec(ctx, blockUser, arg.BlockerPvtID, arg.BlockedPvtID, arg.CreatedAt)
+	return err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (
     user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in
 ) VALUES (
     gen_random_uuid(), $1, $2, $3, $4, $5, $6, NULL
-) RETURNING pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in
+) RETURNING pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in, avatar_id, bio, status_text, status_expires_at, timezone, locale
 `
 
 type CreateUserParams struct {
@@ -49,6 +68,12 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastLoggedIn,
+		&i.AvatarID,
+		&i.Bio,
+		&i.StatusText,
+		&i.StatusExpiresAt,
+		&i.Timezone,
+		&i.Locale,
 	)
 	return i, err
 }
@@ -57,7 +82,7 @@ const deleteUserDetails = `-- name: DeleteUserDetails :one
 DELETE
 FROM users
 WHERE pvt_id = $1
-RETURNING pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in
+RETURNING pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in, avatar_id, bio, status_text, status_expires_at, timezone, locale
 `
 
 func (q *Queries) DeleteUserDetails(ctx context.Context, pvtID int32) (User, error) {
@@ -73,12 +98,18 @@ func (q *Queries) DeleteUserDetails(ctx context.Context, pvtID int32) (User, err
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastLoggedIn,
+		&i.AvatarID,
+		&i.Bio,
+		&i.StatusText,
+		&i.StatusExpiresAt,
+		&i.Timezone,
+		&i.Locale,
 	)
 	return i, err
 }
 
 const getUserById = `-- name: GetUserById :one
-SELECT pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in
+SELECT pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in, avatar_id, bio, status_text, status_expires_at, timezone, locale
 FROM users
 WHERE pvt_id = $1
 `
@@ -96,12 +127,18 @@ func (q *Queries) GetUserById(ctx context.Context, pvtID int32) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastLoggedIn,
+		&i.AvatarID,
+		&i.Bio,
+		&i.StatusText,
+		&i.StatusExpiresAt,
+		&i.Timezone,
+		&i.Locale,
 	)
 	return i, err
 }
 
 const getUserByName = `-- name: GetUserByName :one
-SELECT pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in
+SELECT pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in, avatar_id, bio, status_text, status_expires_at, timezone, locale
 FROM users
 WHERE username = $1
 `
@@ -119,12 +156,18 @@ func (q *Queries) GetUserByName(ctx context.Context, username string) (User, err
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastLoggedIn,
+		&i.AvatarID,
+		&i.Bio,
+		&i.StatusText,
+		&i.StatusExpiresAt,
+		&i.Timezone,
+		&i.Locale,
 	)
 	return i, err
 }
 
 const getUserByNameAndUuid = `-- name: GetUserByNameAndUuid :one
-SELECT pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in
+SELECT pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in, avatar_id, bio, status_text, status_expires_at, timezone, locale
 FROM users
 WHERE user_id = $1 AND username = $2
 `
@@ -147,12 +190,18 @@ func (q *Queries) GetUserByNameAndUuid(ctx context.Context, arg GetUserByNameAnd
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastLoggedIn,
+		&i.AvatarID,
+		&i.Bio,
+		&i.StatusText,
+		&i.StatusExpiresAt,
+		&i.Timezone,
+		&i.Locale,
 	)
 	return i, err
 }
 
 const getUserByUuid = `-- name: GetUserByUuid :one
-SELECT pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in
+SELECT pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in, avatar_id, bio, status_text, status_expires_at, timezone, locale
 FROM users
 WHERE user_id = $1
 `
@@ -170,10 +219,192 @@ func (q *Queries) GetUserByUuid(ctx context.Context, userID pgtype.UUID) (User,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastLoggedIn,
+		&i.AvatarID,
+		&i.Bio,
+		&i.StatusText,
+		&i.StatusExpiresAt,
+		&i.Timezone,
+		&i.Locale,
+	)
+	return i, err
+}
+
+const getUserSettings = `-- name: GetUserSettings :one
+SELECT pvt_id, accept_messages_from, updated_at, last_seen_visibility, send_read_receipts
+FROM user_settings
+WHERE pvt_id = $1
+`
+
+func (q *Queries) GetUserSettings(ctx context.Context, pvtID int32) (UserSetting, error) {
+	row := q.db.QueryRow(ctx, getUserSettings, pvtID)
+	var i UserSetting
+	err := row.Scan(
+		&i.PvtID,
+		&i.AcceptMessagesFrom,
+		&i.UpdatedAt,
+		&i.LastSeenVisibility,
+		&i.SendReadReceipts,
 	)
 	return i, err
 }
 
+const isBlockedBy = `-- name: IsBlockedBy :one
+SELECT EXISTS (
+    SELECT 1
+    FROM user_blocks
+    WHERE blocker_pvt_id = $1 AND blocked_pvt_id = $2
+)
+`
+
+type IsBlockedByParams struct {
+	BlockerPvtID int32 `json:"blocker_pvt_id"`
+	BlockedPvtID int32 `json:"blocked_pvt_id"`
+}
+
+func (q *Queries) IsBlockedBy(ctx context.Context, arg IsBlockedByParams) (bool, error) {
+	row := q.db.QueryRow(ctx, isBlockedBy, arg.BlockerPvtID, arg.BlockedPvtID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const listPresenceTargets = `-- name: ListPresenceTargets :many
+SELECT u.pvt_id, u.user_id, u.last_logged_in,
+    (
+        u.pvt_id = $1
+        OR coalesce(us.last_seen_visibility, 'everyone') = 'everyone'
+        OR (us.last_seen_visibility = 'contacts' AND c.contact_pvt_id IS NOT NULL)
+    )::boolean AS last_seen_visible
+FROM users u
+LEFT JOIN user_settings us ON us.pvt_id = u.pvt_id
+LEFT JOIN contacts c ON c.owner_pvt_id = u.pvt_id AND c.contact_pvt_id = $1
+WHERE u.user_id = ANY($2::uuid[]) AND NOT EXISTS (
+    SELECT 1
+    FROM user_blocks ub
+    WHERE ub.blocker_pvt_id = u.pvt_id AND ub.blocked_pvt_id = $1
+)
+`
+
+type ListPresenceTargetsParams struct {
+	CallerPvtID int32         `json:"caller_pvt_id"`
+	UserIds     []pgtype.UUID `json:"user_ids"`
+}
+
+type ListPresenceTargetsRow struct {
+	PvtID           int32            `json:"pvt_id"`
+	UserID          pgtype.UUID      `json:"user_id"`
+	LastLoggedIn    pgtype.Timestamp `json:"last_logged_in"`
+	LastSeenVisible bool             `json:"last_seen_visible"`
+}
+
+func (q *Queries) ListPresenceTargets(ctx context.Context, arg ListPresenceTargetsParams) ([]ListPresenceTargetsRow, error) {
+	rows, err := q.db.Query(ctx, listPresenceTargets, arg.CallerPvtID, arg.UserIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPresenceTargetsRow
+	for rows.Next() {
+		var i ListPresenceTargetsRow
+		if err := rows.Scan(
+			&i.PvtID,
+			&i.UserID,
+			&i.LastLoggedIn,
+			&i.LastSeenVisible,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT u.pvt_id, u.user_id, u.username, u.display_name, u.password, u.password_salt, u.created_at, u.updated_at, u.last_logged_in, u.avatar_id, u.bio, u.status_text, u.status_expires_at, u.timezone, u.locale
+FROM users u
+WHERE (
+    u.username ILIKE $1::text || '%'
+    OR u.display_name ILIKE $1::text || '%'
+    OR u.username % $2::text
+    OR u.display_name % $2::text
+) AND NOT EXISTS (
+    SELECT 1
+    FROM user_blocks ub
+    WHERE ub.blocker_pvt_id = u.pvt_id AND ub.blocked_pvt_id = $3
+)
+ORDER BY greatest(similarity(u.username, $2::text), similarity(u.display_name, $2::text)) DESC, u.username
+LIMIT $4 OFFSET $5
+`
+
+type SearchUsersParams struct {
+	Prefix      string `json:"prefix"`
+	Query       string `json:"query"`
+	CallerPvtID int32  `json:"caller_pvt_id"`
+	PageLimit   int32  `json:"page_limit"`
+	PageOffset  int32  `json:"page_offset"`
+}
+
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error) {
+	rows, err := q.db.Query(ctx, searchUsers,
+		arg.Prefix,
+		arg.Query,
+		arg.CallerPvtID,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.PvtID,
+			&i.UserID,
+			&i.Username,
+			&i.DisplayName,
+			&i.Password,
+			&i.PasswordSalt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.LastLoggedIn,
+			&i.AvatarID,
+			&i.Bio,
+			&i.StatusText,
+			&i.StatusExpiresAt,
+			&i.Timezone,
+			&i.Locale,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const unblockUser = `-- name: UnblockUser :exec
+DELETE
+FROM user_blocks
+WHERE blocker_pvt_id = $1 AND blocked_pvt_id = $2
+`
+
+type UnblockUserParams struct {
+	BlockerPvtID int32 `json:"blocker_pvt_id"`
+	BlockedPvtID int32 `json:"blocked_pvt_id"`
+}
+
+func (q *Queries) UnblockUser(ctx context.Context, arg UnblockUserParams) error {
+	_, err := q.db.Exec(ctx, unblockUser, arg.BlockerPvtID, arg.BlockedPvtID)
+	return err
+}
+
 const updateLoggedInTime = `-- name: UpdateLoggedInTime :exec
 UPDATE users
 SET last_logged_in = $1
@@ -190,19 +421,61 @@ func (q *Queries) UpdateLoggedInTime(ctx context.Context, arg UpdateLoggedInTime
 	return err
 }
 
+const updateUserAvatar = `-- name: UpdateUserAvatar :one
+UPDATE users
+SET avatar_id = $1, updated_at = $2
+WHERE pvt_id = $3
+RETURNING pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in, avatar_id, bio, status_text, status_expires_at, timezone, locale
+`
+
+type UpdateUserAvatarParams struct {
+	AvatarID  pgtype.UUID `json:"avatar_id"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	PvtID     int32       `json:"pvt_id"`
+}
+
+func (q *Queries) UpdateUserAvatar(ctx context.Context, arg UpdateUserAvatarParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserAvatar, arg.AvatarID, arg.UpdatedAt, arg.PvtID)
+	var i User
+	err := row.Scan(
+		&i.PvtID,
+		&i.UserID,
+		&i.Username,
+		&i.DisplayName,
+		&i.Password,
+		&i.PasswordSalt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.LastLoggedIn,
+		&i.AvatarID,
+		&i.Bio,
+		&i.StatusText,
+		&i.StatusExpiresAt,
+		&i.Timezone,
+		&i.Locale,
+	)
+	return i, err
+}
+
 const updateUserDetails = `-- name: UpdateUserDetails :one
 UPDATE users
-SET username = $1, display_name = $2, password = $3, updated_at = $4
-WHERE pvt_id = $5
-RETURNING pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in
+SET username = $1, display_name = $2, password = $3, updated_at = $4,
+    bio = $5, status_text = $6, status_expires_at = $7, timezone = $8, locale = $9
+WHERE pvt_id = $10
+RETURNING pvt_id, user_id, username, display_name, password, password_salt, created_at, updated_at, last_logged_in, avatar_id, bio, status_text, status_expires_at, timezone, locale
 `
 
 type UpdateUserDetailsParams struct {
-	Username    string    `json:"username"`
-	DisplayName string    `json:"display_name"`
-	Password    []byte    `json:"password"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	PvtID       int32     `json:"pvt_id"`
+	Username        string           `json:"username"`
+	DisplayName     string           `json:"display_name"`
+	Password        []byte           `json:"password"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+	Bio             string           `json:"bio"`
+	StatusText      string           `json:"status_text"`
+	StatusExpiresAt pgtype.Timestamp `json:"status_expires_at"`
+	Timezone        string           `json:"timezone"`
+	Locale          string           `json:"locale"`
+	PvtID           int32            `json:"pvt_id"`
 }
 
 func (q *Queries) UpdateUserDetails(ctx context.Context, arg UpdateUserDetailsParams) (User, error) {
@@ -211,6 +484,11 @@ func (q *Queries) UpdateUserDetails(ctx context.Context, arg UpdateUserDetailsPa
 		arg.DisplayName,
 		arg.Password,
 		arg.UpdatedAt,
+		arg.Bio,
+		arg.StatusText,
+		arg.StatusExpiresAt,
+		arg.Timezone,
+		arg.Locale,
 		arg.PvtID,
 	)
 	var i User
@@ -224,6 +502,52 @@ func (q *Queries) UpdateUserDetails(ctx context.Context, arg UpdateUserDetailsPa
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.LastLoggedIn,
+		&i.AvatarID,
+		&i.Bio,
+		&i.StatusText,
+		&i.StatusExpiresAt,
+		&i.Timezone,
+		&i.Locale,
+	)
+	return i, err
+}
+
+const upsertUserSettings = `-- name: UpsertUserSettings :one
+INSERT INTO user_settings (
+    pvt_id, accept_messages_from, last_seen_visibility, send_read_receipts, updated_at
+) VALUES (
+    $1, $2, $3, $4, $5
+) ON CONFLICT (pvt_id) DO UPDATE
+SET accept_messages_from = EXCLUDED.accept_messages_from,
+    last_seen_visibility = EXCLUDED.last_seen_visibility,
+    send_read_receipts = EXCLUDED.send_read_receipts,
+    updated_at = EXCLUDED.updated_at
+RETURNING pvt_id, accept_messages_from, updated_at, last_seen_visibility, send_read_receipts
+`
+
+type UpsertUserSettingsParams struct {
+	PvtID              int32          `json:"pvt_id"`
+	AcceptMessagesFrom MessagePrivacy `json:"accept_messages_from"`
+	LastSeenVisibility Visibility     `json:"last_seen_visibility"`
+	SendReadReceipts   bool           `json:"send_read_receipts"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+}
+
+func (q *Queries) UpsertUserSettings(ctx context.Context, arg UpsertUserSettingsParams) (UserSetting, error) {
+	row := q.db.QueryRow(ctx, upsertUserSettings,
+		arg.PvtID,
+		arg.AcceptMessagesFrom,
+		arg.LastSeenVisibility,
+		arg.SendReadReceipts,
+		arg.UpdatedAt,
+	)
+	var i UserSetting
+	err := row.Scan(
+		&i.PvtID,
+		&i.AcceptMessagesFrom,
+		&i.UpdatedAt,
+		&i.LastSeenVisibility,
+		&i.SendReadReceipts,
 	)
 	return i, err
 }
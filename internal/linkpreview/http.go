@@ -0,0 +1,127 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/netguard"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	fetchTimeout  = 5 * time.Second
+	maxPageBytes  = 512 << 10
+	maxRedirects  = 3
+	userAgent     = "chat-api-link-preview/1.0"
+	acceptedTypes = "text/html,application/xhtml+xml"
+)
+
+// HTTPFetcher loads pages from the internet. The address is checked when the
+// connection is dialed, see netguard.DenyInternal.
+type HTTPFetcher struct {
+	Client   *http.Client
+	MaxBytes int64
+}
+
+func NewHTTPFetcher() *HTTPFetcher {
+	dialer := &net.Dialer{
+		Timeout: fetchTimeout,
+		Control: denyInternal,
+	}
+	transport := &http.Transport{
+		// a proxy would dial on our behalf and skip the check
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   fetchTimeout,
+		ResponseHeaderTimeout: fetchTimeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	return &HTTPFetcher{
+		Client: &http.Client{
+			Transport:     transport,
+			Timeout:       fetchTimeout,
+			CheckRedirect: checkRedirect,
+		},
+		MaxBytes: maxPageBytes,
+	}
+}
+
+// denyInternal only lets connections to public addresses on the web ports
+// through
+func denyInternal(network, address string, c syscall.RawConn) error {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if port != "80" && port != "443" {
+		return ErrBlockedTarget
+	}
+	if netguard.DenyInternal(network, address, c) != nil {
+		return ErrBlockedTarget
+	}
+	return nil
+}
+
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrBlockedTarget
+	}
+	return nil
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errors.New("too many redirects")
+	}
+	return checkURL(req.URL)
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, link string) (Preview, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return Preview{}, err
+	}
+	err = checkURL(u)
+	if err != nil {
+		return Preview{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", acceptedTypes)
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return Preview{}, ErrNotHTML
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.MaxBytes), contentType)
+	if err != nil {
+		return Preview{}, err
+	}
+	p, err := Parse(body, resp.Request.URL)
+	if err != nil {
+		return Preview{}, err
+	}
+	p.URL = link
+	return p, nil
+}
@@ -0,0 +1,151 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	ErrNotHTML       = errors.New("link is not an html page")
+	ErrNoPreview     = errors.New("page has nothing to preview")
+	ErrBlockedTarget = errors.New("link points to a blocked address")
+)
+
+// maxFieldLength keeps a page from stuffing a huge title into every message
+const maxFieldLength = 500
+
+// Preview is what a page says about itself in its OpenGraph tags, with the
+// html title and description as fallback
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// Fetcher loads the preview of a link, tests can swap in their own
+type Fetcher interface {
+	Fetch(ctx context.Context, link string) (Preview, error)
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'\x60]+`)
+
+// FirstURL is the link of a message body that gets a preview, trailing
+// punctuation is taken as part of the sentence
+func FirstURL(body string) (string, bool) {
+	for _, match := range urlPattern.FindAllString(body, -1) {
+		match = strings.TrimRight(match, ".,;:!?)]}*_~")
+		u, err := url.Parse(match)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		u.Fragment = ""
+		return u.String(), true
+	}
+	return "", false
+}
+
+// Parse reads the head of an html page, base resolves a relative image url
+func Parse(r io.Reader, base *url.URL) (Preview, error) {
+	p := Preview{URL: base.String()}
+	var htmlTitle, htmlDescription string
+	z := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if errors.Is(z.Err(), io.EOF) {
+				return finish(p, htmlTitle, htmlDescription)
+			}
+			return Preview{}, z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.DataAtom {
+			case atom.Body:
+				return finish(p, htmlTitle, htmlDescription)
+			case atom.Title:
+				inTitle = tt == html.StartTagToken
+			case atom.Meta:
+				var property, name, content string
+				for _, a := range t.Attr {
+					switch strings.ToLower(a.Key) {
+					case "property":
+						property = strings.ToLower(a.Val)
+					case "name":
+						name = strings.ToLower(a.Val)
+					case "content":
+						content = a.Val
+					}
+				}
+				switch {
+				case property == "og:title":
+					p.Title = content
+				case property == "og:description":
+					p.Description = content
+				case property == "og:image" || property == "og:image:url":
+					if p.ImageURL == "" {
+						p.ImageURL = resolveImage(base, content)
+					}
+				case property == "og:site_name":
+					p.SiteName = content
+				case name == "description":
+					htmlDescription = content
+				}
+			}
+		case html.EndTagToken:
+			t := z.Token()
+			if t.DataAtom == atom.Head {
+				return finish(p, htmlTitle, htmlDescription)
+			}
+			if t.DataAtom == atom.Title {
+				inTitle = false
+			}
+		case html.TextToken:
+			if inTitle && htmlTitle == "" {
+				htmlTitle = string(z.Text())
+			}
+		}
+	}
+}
+
+func finish(p Preview, htmlTitle, htmlDescription string) (Preview, error) {
+	if p.Title == "" {
+		p.Title = htmlTitle
+	}
+	if p.Description == "" {
+		p.Description = htmlDescription
+	}
+	p.Title = clip(p.Title)
+	p.Description = clip(p.Description)
+	p.SiteName = clip(p.SiteName)
+	if p.Title == "" && p.Description == "" && p.ImageURL == "" {
+		return Preview{}, ErrNoPreview
+	}
+	return p, nil
+}
+
+// resolveImage only keeps web images, clients load them as they are
+func resolveImage(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+func clip(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len([]rune(s)) > maxFieldLength {
+		s = string([]rune(s)[:maxFieldLength]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,234 @@
+package presence
+
+import (
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Status string
+
+const (
+	StatusOnline  Status = "online"
+	StatusAway    Status = "away"
+	StatusOffline Status = "offline"
+)
+
+const (
+	// FlushInterval is how often the registry writes to Postgres
+	FlushInterval = 15 * time.Second
+	// connections not refreshed for this long belong to a dead instance
+	staleAfter = 4 * FlushInterval
+	// AwayAfter is the idle time after which a connected user is away
+	AwayAfter = 5 * time.Minute
+)
+
+type Presence struct {
+	Status       Status
+	LastActiveAt time.Time
+}
+
+type entry struct {
+	connections int32
+	lastActive  time.Time
+	dirty       bool
+}
+
+// Registry tracks the presence of users on this instance. The state is
+// synced through Postgres, so lookups see the users of every instance.
+type Registry struct {
+	mu         sync.Mutex
+	instanceId pgtype.UUID
+	users      map[int32]*entry
+	connPool   *pgxpool.Pool
+}
+
+func NewRegistry(connPool *pgxpool.Pool) (*Registry, error) {
+	instanceId := pgtype.UUID{Valid: true}
+	_, err := rand.Read(instanceId.Bytes[:])
+	if err != nil {
+		return nil, err
+	}
+	return &Registry{
+		instanceId: instanceId,
+		users:      make(map[int32]*entry),
+		connPool:   connPool,
+	}, nil
+}
+
+func (r *Registry) entry(pvtId int32) *entry {
+	e, ok := r.users[pvtId]
+	if !ok {
+		e = &entry{}
+		r.users[pvtId] = e
+	}
+	return e
+}
+
+// Touch records activity of an authenticated user
+func (r *Registry) Touch(pvtId int32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.entry(pvtId)
+	e.lastActive = time.Now().UTC()
+	e.dirty = true
+}
+
+func (r *Registry) Connect(pvtId int32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.entry(pvtId)
+	e.connections++
+	e.lastActive = time.Now().UTC()
+	e.dirty = true
+}
+
+func (r *Registry) Disconnect(pvtId int32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.entry(pvtId)
+	e.connections = max(e.connections-1, 0)
+	e.lastActive = time.Now().UTC()
+	e.dirty = true
+}
+
+// Run flushes the registry periodically until the context is done
+func (r *Registry) Run(ctx context.Context) {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.shutdown()
+			return
+		case <-ticker.C:
+			err := r.flush(ctx)
+			if err != nil {
+				slog.Error("could not flush presence registry", "error", err)
+			}
+		}
+	}
+}
+
+func (r *Registry) flush(ctx context.Context) error {
+	now := time.Now().UTC()
+	activeIds, activeAts := []int32{}, []time.Time{}
+	connectedIds, connections := []int32{}, []int32{}
+
+	r.mu.Lock()
+	for pvtId, e := range r.users {
+		if e.dirty {
+			activeIds = append(activeIds, pvtId)
+			activeAts = append(activeAts, e.lastActive)
+			e.dirty = false
+		}
+		if e.connections > 0 {
+			connectedIds = append(connectedIds, pvtId)
+			connections = append(connections, e.connections)
+		} else {
+			delete(r.users, pvtId)
+		}
+	}
+	r.mu.Unlock()
+
+	queries := database.New(r.connPool)
+	err := queries.UpsertLastActive(ctx, database.UpsertLastActiveParams{
+		PvtIds:        activeIds,
+		LastActiveAts: activeAts,
+	})
+	if err != nil {
+		r.restore(activeIds, activeAts)
+		return err
+	}
+	err = queries.UpsertPresenceConnections(ctx, database.UpsertPresenceConnectionsParams{
+		InstanceID:  r.instanceId,
+		HeartbeatAt: now,
+		PvtIds:      connectedIds,
+		Connections: connections,
+	})
+	if err != nil {
+		return err
+	}
+	err = queries.DeleteInstanceConnections(ctx, database.DeleteInstanceConnectionsParams{
+		InstanceID: r.instanceId,
+		KeepPvtIds: connectedIds,
+	})
+	if err != nil {
+		return err
+	}
+	return queries.PruneStaleConnections(ctx, now.Add(-staleAfter))
+}
+
+// restore marks activity that could not be written as dirty again
+func (r *Registry) restore(pvtIds []int32, activeAts []time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, pvtId := range pvtIds {
+		e := r.entry(pvtId)
+		if activeAts[i].After(e.lastActive) {
+			e.lastActive = activeAts[i]
+		}
+		e.dirty = true
+	}
+}
+
+// shutdown persists the last activity and drops the connections of this
+// instance
+func (r *Registry) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r.mu.Lock()
+	for _, e := range r.users {
+		e.connections = 0
+	}
+	r.mu.Unlock()
+	err := r.flush(ctx)
+	if err != nil {
+		slog.Error("could not flush presence registry on shutdown", "error", err)
+	}
+}
+
+// Lookup combines the synced state of all instances with the not yet
+// flushed state of this one
+func (r *Registry) Lookup(ctx context.Context, pvtIds []int32) (map[int32]Presence, error) {
+	now := time.Now().UTC()
+	queries := database.New(r.connPool)
+	rows, err := queries.GetPresence(ctx, database.GetPresenceParams{
+		PvtIds:     pvtIds,
+		FreshAfter: now.Add(-staleAfter),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make(map[int32]Presence, len(rows))
+	for _, row := range rows {
+		lastActive := row.LastActiveAt.Time
+		connected := row.Connections > 0
+		if e, ok := r.users[row.PvtID]; ok {
+			if e.lastActive.After(lastActive) {
+				lastActive = e.lastActive
+			}
+			connected = connected || e.connections > 0
+		}
+		status := StatusOffline
+		if !lastActive.IsZero() && now.Sub(lastActive) < AwayAfter {
+			status = StatusOnline
+		} else if connected {
+			status = StatusAway
+		}
+		result[row.PvtID] = Presence{
+			Status:       status,
+			LastActiveAt: lastActive,
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// idle keys are dropped once every sweepInterval
+const sweepInterval = time.Minute
+
+type entry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter keeps a token bucket for every user of this instance
+type Limiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	users     map[int32]*entry
+	lastSweep time.Time
+}
+
+func New(every time.Duration, burst int) *Limiter {
+	return &Limiter{
+		limit:     rate.Every(every),
+		burst:     burst,
+		users:     make(map[int32]*entry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow reports whether the user may perform one more action now
+func (l *Limiter) Allow(pvtId int32) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+	e, ok := l.users[pvtId]
+	if !ok {
+		e = &entry{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.users[pvtId] = e
+	}
+	e.lastSeen = now
+	return e.limiter.AllowN(now, 1)
+}
+
+// sweep forgets users whose bucket has refilled completely, they start
+// again with a full bucket anyway
+func (l *Limiter) sweep(now time.Time) {
+	refill := time.Duration(float64(l.burst) / float64(l.limit) * float64(time.Second))
+	for pvtId, e := range l.users {
+		if now.Sub(e.lastSeen) > refill {
+			delete(l.users, pvtId)
+		}
+	}
+	l.lastSweep = now
+}
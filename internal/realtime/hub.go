@@ -0,0 +1,229 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	subscriptionBuffer = 32
+	// notifyChannel carries events between the instances sharing a database
+	notifyChannel = "chat_api_events"
+	// Postgres refuses notification payloads of 8000 bytes or more
+	maxNotifyPayload = 7999
+	listenRetryDelay = 5 * time.Second
+)
+
+// Event is pushed to every open real-time connection of its recipients
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type Subscription struct {
+	pvtId  int32
+	Events chan Event
+}
+
+// envelope is the notification sent to the other instances. An event too
+// large for a notification is stored and only its StoredID is sent.
+type envelope struct {
+	Origin   string  `json:"origin"`
+	PvtIds   []int32 `json:"pvt_ids,omitempty"`
+	Event    Event   `json:"event"`
+	StoredID int64   `json:"stored_id,omitempty"`
+}
+
+// Hub keeps track of the real-time connections held by this instance and
+// relays events to the other instances through Postgres notifications
+type Hub struct {
+	mu         sync.RWMutex
+	subs       map[int32]map[*Subscription]struct{}
+	closed     bool
+	instanceId string
+	connPool   *pgxpool.Pool
+}
+
+func NewHub(connPool *pgxpool.Pool) (*Hub, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	return &Hub{
+		subs:       make(map[int32]map[*Subscription]struct{}),
+		instanceId: hex.EncodeToString(id),
+		connPool:   connPool,
+	}, nil
+}
+
+func (h *Hub) Subscribe(pvtId int32) *Subscription {
+	sub := &Subscription{
+		pvtId:  pvtId,
+		Events: make(chan Event, subscriptionBuffer),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(sub.Events)
+		return sub
+	}
+	if h.subs[pvtId] == nil {
+		h.subs[pvtId] = make(map[*Subscription]struct{})
+	}
+	h.subs[pvtId][sub] = struct{}{}
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// the events of every subscription are already closed once the hub stops
+	if _, ok := h.subs[sub.pvtId][sub]; !ok {
+		return
+	}
+	delete(h.subs[sub.pvtId], sub)
+	if len(h.subs[sub.pvtId]) == 0 {
+		delete(h.subs, sub.pvtId)
+	}
+	close(sub.Events)
+}
+
+// Publish sends an event to every connection of the given users, on this
+// and every other instance
+func (h *Hub) Publish(ctx context.Context, pvtIds []int32, eventType string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	ev := Event{Type: eventType, Data: raw}
+	h.deliver(pvtIds, ev)
+
+	payload, err := json.Marshal(envelope{Origin: h.instanceId, PvtIds: pvtIds, Event: ev})
+	if err != nil {
+		return err
+	}
+	queries := database.New(h.connPool)
+	if len(payload) > maxNotifyPayload {
+		storedId, err := queries.StoreRealtimeEvent(ctx, database.StoreRealtimeEventParams{
+			Payload:   string(payload),
+			CreatedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+		payload, err = json.Marshal(envelope{Origin: h.instanceId, StoredID: storedId})
+		if err != nil {
+			return err
+		}
+	}
+	return queries.NotifyEvent(ctx, database.NotifyEventParams{
+		Channel: notifyChannel,
+		Payload: string(payload),
+	})
+}
+
+// deliver never blocks, slow connections lose events instead of holding up
+// the publisher
+func (h *Hub) deliver(pvtIds []int32, ev Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, pvtId := range pvtIds {
+		for sub := range h.subs[pvtId] {
+			select {
+			case sub.Events <- ev:
+			default:
+				slog.Warn("dropping event for slow connection", "pvt_id", pvtId, "type", ev.Type)
+			}
+		}
+	}
+}
+
+// Run listens for events published by the other instances until the
+// context is done, then closes the events of every subscription so the
+// connections holding them end as well
+func (h *Hub) Run(ctx context.Context) {
+	defer h.close()
+	for {
+		err := h.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("lost event notifications, retrying", "error", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (h *Hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, subs := range h.subs {
+		for sub := range subs {
+			close(sub.Events)
+		}
+	}
+	h.subs = make(map[int32]map[*Subscription]struct{})
+}
+
+func (h *Hub) listen(ctx context.Context) error {
+	c, err := h.connPool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// the listening connection is never handed back to the pool
+	conn := c.Hijack()
+	defer conn.Close(context.Background())
+
+	_, err = conn.Exec(ctx, "LISTEN "+notifyChannel)
+	if err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		env := envelope{}
+		err = json.Unmarshal([]byte(n.Payload), &env)
+		if err != nil {
+			slog.Warn("could not decode event notification", "error", err)
+			continue
+		}
+		if env.Origin == h.instanceId {
+			continue
+		}
+		if env.StoredID != 0 {
+			env, err = h.loadStored(ctx, env.StoredID)
+			if err != nil {
+				slog.Warn("could not load stored event", "stored_id", env.StoredID, "error", err)
+				continue
+			}
+		}
+		h.deliver(env.PvtIds, env.Event)
+	}
+}
+
+// loadStored fetches an event that was too large for its notification
+func (h *Hub) loadStored(ctx context.Context, storedId int64) (envelope, error) {
+	env := envelope{StoredID: storedId}
+	queries := database.New(h.connPool)
+	payload, err := queries.GetRealtimeEvent(ctx, storedId)
+	if err != nil {
+		return env, err
+	}
+	err = json.Unmarshal([]byte(payload), &env)
+	return env, err
+}
@@ -0,0 +1,129 @@
+package richtext
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/rivo/uniseg"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Clean drops control characters other than tabs and newlines, line endings
+// become \n. Bidi overrides are dropped too, they can make text display
+// differently than it reads.
+func Clean(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\t':
+			return r
+		case r == '\r':
+			return '\n'
+		case unicode.IsControl(r):
+			return -1
+		case r >= '\u202a' && r <= '\u202e', r >= '\u2066' && r <= '\u2069', r == '\ufeff':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Length counts user perceived characters, an emoji made of several code
+// points counts once
+func Length(s string) int {
+	return uniseg.GraphemeClusterCount(s)
+}
+
+// restrictSubset rewrites the parts of markdown a chat message does not
+// support into plain inline text: headings become paragraphs, images their
+// alt text, and raw html and rules are dropped
+type restrictSubset struct{}
+
+func (restrictSubset) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var unsupported []ast.Node
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case ast.KindHeading, ast.KindImage, ast.KindHTMLBlock, ast.KindRawHTML, ast.KindThematicBreak:
+			unsupported = append(unsupported, n)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, n := range unsupported {
+		parent := n.Parent()
+		if parent == nil {
+			continue
+		}
+		switch n.Kind() {
+		case ast.KindHeading:
+			p := ast.NewParagraph()
+			moveChildren(n, p)
+			parent.ReplaceChild(parent, n, p)
+		case ast.KindImage:
+			for c := n.FirstChild(); c != nil; {
+				next := c.NextSibling()
+				parent.InsertBefore(parent, n, c)
+				c = next
+			}
+			parent.RemoveChild(parent, n)
+		default:
+			parent.RemoveChild(parent, n)
+		}
+	}
+}
+
+func moveChildren(from, to ast.Node) {
+	for c := from.FirstChild(); c != nil; {
+		next := c.NextSibling()
+		to.AppendChild(to, c)
+		c = next
+	}
+}
+
+var (
+	markdown = goldmark.New(
+		goldmark.WithExtensions(extension.Strikethrough, extension.Linkify),
+		goldmark.WithParserOptions(
+			parser.WithASTTransformers(util.Prioritized(restrictSubset{}, 100)),
+		),
+	)
+	policy = newPolicy()
+)
+
+// newPolicy allows what the supported subset renders to, it is the last line
+// of defence should the renderer ever let something else through
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+	p.AllowElements("p", "br", "em", "strong", "del", "code", "pre", "blockquote", "ul", "ol", "li", "a")
+	p.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowURLSchemes("http", "https", "mailto")
+	p.RequireParseableURLs(true)
+	p.RequireNoFollowOnLinks(true)
+	p.RequireNoReferrerOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}
+
+// RenderMarkdown turns a markdown body into sanitized html. Emphasis, code,
+// quotes, lists, links and strikethrough are supported, anything else is
+// shown as text or left out.
+func RenderMarkdown(source string) (string, error) {
+	var buf bytes.Buffer
+	err := markdown.Convert([]byte(source), &buf)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(policy.Sanitize(buf.String())), nil
+}
@@ -0,0 +1,166 @@
+// Package webhook signs and posts events to the endpoints users subscribe
+// with. The queue of deliveries lives in Postgres, this package only knows
+// how to send one.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/netguard"
+)
+
+// headers sent with every delivery, receivers check SignatureHeader with
+// Verify before trusting the body
+const (
+	EventHeader     = "X-Chat-Api-Event"
+	DeliveryHeader  = "X-Chat-Api-Delivery"
+	TimestampHeader = "X-Chat-Api-Timestamp"
+	SignatureHeader = "X-Chat-Api-Signature"
+)
+
+// SendTimeout bounds a whole delivery, from dialing to reading the reply
+const SendTimeout = 10 * time.Second
+
+const (
+	maxReplyBytes   = 64 << 10
+	userAgent       = "chat-api-webhook/1.0"
+	signaturePrefix = "sha256="
+	firstBackoff    = 30 * time.Second
+	maxBackoff      = 6 * time.Hour
+)
+
+// Delivery is one event on its way to an endpoint
+type Delivery struct {
+	ID     int64
+	Event  string
+	URL    string
+	Secret string
+	Body   []byte
+}
+
+// StatusError is an answer outside of 2xx
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("endpoint answered with status %d", e.StatusCode)
+}
+
+// Sender posts deliveries. Tests can swap in the client of an
+// httptest.Server.
+type Sender struct {
+	Client *http.Client
+}
+
+// NewSender refuses endpoints inside the server's network unless
+// allowPrivate is set, which is meant for receivers running next to a
+// development setup
+func NewSender(allowPrivate bool) *Sender {
+	dialer := &net.Dialer{Timeout: SendTimeout}
+	if !allowPrivate {
+		dialer.Control = netguard.DenyInternal
+	}
+	transport := &http.Transport{
+		// a proxy would dial on our behalf and skip the check
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   SendTimeout,
+		ResponseHeaderTimeout: SendTimeout,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       time.Minute,
+	}
+	return &Sender{
+		Client: &http.Client{
+			Transport: transport,
+			Timeout:   SendTimeout,
+			// a redirect is an answer like any other 3xx, the endpoint has to
+			// be given as it is
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// SetupSender reads CHAT_API_WEBHOOK_ALLOW_PRIVATE to decide if endpoints
+// on private addresses are allowed
+func SetupSender() (*Sender, error) {
+	allowPrivate := false
+	if val, ok := os.LookupEnv("CHAT_API_WEBHOOK_ALLOW_PRIVATE"); ok {
+		var err error
+		allowPrivate, err = strconv.ParseBool(val)
+		if err != nil {
+			return nil, fmt.Errorf("could not understand CHAT_API_WEBHOOK_ALLOW_PRIVATE: %s", val)
+		}
+	}
+	return NewSender(allowPrivate), nil
+}
+
+// Sign is the HMAC-SHA256 of the timestamp and the body joined by a dot,
+// written as hex after "sha256=". The timestamp is part of it so a captured
+// delivery cannot be replayed later with a new one.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature the way a receiver should, in constant time
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Backoff is the wait after the given number of failed attempts. It doubles
+// from 30 seconds up to 6 hours, with up to a fifth added at random so
+// deliveries failing together do not all come back together.
+func Backoff(attempts int32) time.Duration {
+	wait := maxBackoff
+	if attempts < 1 {
+		wait = firstBackoff
+	} else if attempts < 20 {
+		wait = min(firstBackoff<<(attempts-1), maxBackoff)
+	}
+	return wait + rand.N(wait/5)
+}
+
+// Send posts a delivery and reports the status it was answered with, 0 when
+// there was no answer. Anything but a 2xx is an error.
+func (s *Sender) Send(ctx context.Context, d Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(d.Secret, timestamp, d.Body))
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// reading the answer lets the connection be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxReplyBytes))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/internal/linkpreview"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	unfurlerInterval        = 2 * time.Second
+	unfurlerBatchSize int32 = 20
+	unfurlTimeout           = 10 * time.Second
+	// claimed links are left alone this long, well past the time the
+	// fetches can take, so a crashed unfurler only delays them
+	unfurlerLease = time.Minute
+	// a preview is reused for a day, a failed fetch is retried sooner
+	previewCacheTtl       = 24 * time.Hour
+	failedPreviewCacheTtl = time.Hour
+)
+
+// PublicLinkPreview is the card shown under a message for the first link in
+// its body
+type PublicLinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+func convertToPublicLinkPreview(m database.MessagePublic) *PublicLinkPreview {
+	if !m.PreviewUrl.Valid {
+		return nil
+	}
+	return &PublicLinkPreview{
+		URL:         m.PreviewUrl.String,
+		Title:       m.PreviewTitle.String,
+		Description: m.PreviewDescription.String,
+		ImageURL:    m.PreviewImageUrl.String,
+		SiteName:    m.PreviewSiteName.String,
+	}
+}
+
+// queueLinkPreview leaves the first link of a new message for the unfurler,
+// sending a message never waits on somebody else's server
+func queueLinkPreview(ctx context.Context, queries *database.Queries, mssgId int64, body string, createdAt time.Time) error {
+	link, ok := linkpreview.FirstURL(body)
+	if !ok {
+		return nil
+	}
+	return queries.QueueLinkPreviews(ctx, database.QueueLinkPreviewsParams{
+		MssgIds:   []int64{mssgId},
+		Urls:      []string{link},
+		CreatedAt: createdAt,
+	})
+}
+
+// runUnfurler fetches the previews of queued links and pushes the messages
+// again once they have one
+func runUnfurler(ctx context.Context, apiCfg apiconf.ApiConfig) {
+	runBatches(ctx, "unfurler", unfurlerInterval, unfurlerBatchSize, func(ctx context.Context) (int32, error) {
+		return unfurlPendingLinks(ctx, apiCfg)
+	})
+}
+
+// cachedPreview is the stored preview of a link if it is recent enough to
+// be used again
+func cachedPreview(ctx context.Context, queries *database.Queries, link string, now time.Time) (database.LinkPreview, bool, error) {
+	preview, err := queries.GetLinkPreview(ctx, database.GetLinkPreviewParams{
+		Url:        link,
+		FreshAfter: now.Add(-previewCacheTtl),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return database.LinkPreview{}, false, nil
+	} else if err != nil {
+		return database.LinkPreview{}, false, err
+	}
+	if !preview.Ok && preview.FetchedAt.Before(now.Add(-failedPreviewCacheTtl)) {
+		return database.LinkPreview{}, false, nil
+	}
+	return preview, true, nil
+}
+
+// fetchPreviews loads the links not found in the cache at the same time,
+// a link that cannot be previewed is stored as failed
+func fetchPreviews(ctx context.Context, fetcher linkpreview.Fetcher, links []string, now time.Time) []database.SaveLinkPreviewParams {
+	ctx, cancel := context.WithTimeout(ctx, unfurlTimeout)
+	defer cancel()
+	previews := make([]database.SaveLinkPreviewParams, len(links))
+	wg := sync.WaitGroup{}
+	for n, link := range links {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			previews[n] = database.SaveLinkPreviewParams{Url: link, FetchedAt: now}
+			p, err := fetcher.Fetch(ctx, link)
+			if err != nil {
+				slog.Debug("could not fetch link preview", "url", link, "error", err)
+				return
+			}
+			previews[n] = database.SaveLinkPreviewParams{
+				Url:         link,
+				Ok:          true,
+				Title:       p.Title,
+				Description: p.Description,
+				ImageUrl:    p.ImageURL,
+				SiteName:    p.SiteName,
+				FetchedAt:   now,
+			}
+		}()
+	}
+	wg.Wait()
+	return previews
+}
+
+// unfurlPendingLinks handles one batch of queued links, messages sharing a
+// link share the fetch. Claiming moves the links out of reach of other
+// instances for the lease, no transaction is open during the fetches.
+func unfurlPendingLinks(ctx context.Context, apiCfg apiconf.ApiConfig) (int32, error) {
+	queries := database.New(apiCfg.ConnPool)
+	now := time.Now().UTC()
+	pending, err := queries.ClaimPendingLinkPreviews(ctx, database.ClaimPendingLinkPreviewsParams{
+		LeaseUntil: pgtype.Timestamp{Time: now.Add(unfurlerLease), Valid: true},
+		Now:        pgtype.Timestamp{Time: now, Valid: true},
+		BatchSize:  unfurlerBatchSize,
+	})
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+	ok := make(map[string]bool)
+	var toFetch []string
+	for _, p := range pending {
+		if _, seen := ok[p.Url]; seen {
+			continue
+		}
+		preview, found, err := cachedPreview(ctx, queries, p.Url, now)
+		if err != nil {
+			return 0, err
+		}
+		ok[p.Url] = preview.Ok
+		if !found {
+			toFetch = append(toFetch, p.Url)
+		}
+	}
+	previews := fetchPreviews(ctx, apiCfg.Previews, toFetch, now)
+	// fetches cut short by a shutdown are not the link's fault, the lease
+	// runs out and they are fetched again
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+
+	c, err := apiCfg.ConnPool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Release()
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	txQueries := queries.WithTx(tx)
+	for _, preview := range previews {
+		err = txQueries.SaveLinkPreview(ctx, preview)
+		if err != nil {
+			return 0, err
+		}
+		ok[preview.Url] = preview.Ok
+	}
+	mssgIds := make([]int64, 0, len(pending))
+	for _, p := range pending {
+		mssgIds = append(mssgIds, p.MssgID)
+	}
+	err = txQueries.MarkLinkPreviewsUnfurled(ctx, database.MarkLinkPreviewsUnfurledParams{
+		UnfurledAt: timestampNow(),
+		MssgIds:    mssgIds,
+	})
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	slog.Debug("unfurled links", "count", len(pending), "fetched", len(toFetch))
+
+	// the preview reaches clients as an update of the message
+	for _, p := range pending {
+		if !ok[p.Url] {
+			continue
+		}
+		m, err := queries.GetMessageByIdPublic(ctx, p.MssgID)
+		if err != nil {
+			slog.Warn("could not load unfurled message", "mssg_id", p.MssgID, "error", err)
+			continue
+		}
+		publishMessage(ctx, apiCfg.Hub, m, p.FromPvtID, p.ToPvtID)
+	}
+	return int32(len(pending)), nil
+}
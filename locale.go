@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	ut "github.com/go-playground/universal-translator"
+)
+
+// requestTranslator uses the preferred locale of the logged in user and the
+// Accept-Language header for anonymous requests
+func requestTranslator(r *http.Request) ut.Translator {
+	apiCfg := apiconf.GetConfig(r)
+	if user, ok := auth.LookupUserData(r); ok {
+		return apiCfg.Localizer.Translator(user.Locale)
+	}
+	return apiCfg.Localizer.Translator(r.Header.Get("Accept-Language"))
+}
+
+// userLocation is the time zone server generated content should be rendered
+// in for the user
+func userLocation(u database.User) *time.Location {
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	liveLocationEndedError = "live location has ended"
+	notLocationSenderError = "only the sender can change a shared location"
+)
+
+var errContactNotFound = errors.New("could not find user to share")
+
+// createLocationData is a shared location, a location with live_seconds is
+// shared live and can be moved by the sender until it ends
+type createLocationData struct {
+	Latitude       *float64 `json:"latitude"        validate:"required,min=-90,max=90"`
+	Longitude      *float64 `json:"longitude"       validate:"required,min=-180,max=180"`
+	AccuracyMeters float64  `json:"accuracy_meters" validate:"omitempty,gt=0,max=100000"`
+	LiveSeconds    int32    `json:"live_seconds"    validate:"omitempty,min=60,max=28800"`
+}
+
+// createContactData shares the contact card of another user
+type createContactData struct {
+	UserId pgtype.UUID `json:"user_id" validate:"required"`
+}
+
+// newLocation holds the location of a message about to be written
+type newLocation struct {
+	Latitude       float64
+	Longitude      float64
+	AccuracyMeters pgtype.Float8
+	LiveUntil      pgtype.Timestamp
+}
+
+func convertToNewLocation(l *createLocationData, now time.Time) *newLocation {
+	if l == nil {
+		return nil
+	}
+	liveUntil := pgtype.Timestamp{}
+	if l.LiveSeconds != 0 {
+		liveUntil = pgtype.Timestamp{Time: now.Add(time.Duration(l.LiveSeconds) * time.Second), Valid: true}
+	}
+	return &newLocation{
+		Latitude:       *l.Latitude,
+		Longitude:      *l.Longitude,
+		AccuracyMeters: pgtype.Float8{Float64: l.AccuracyMeters, Valid: l.AccuracyMeters != 0},
+		LiveUntil:      liveUntil,
+	}
+}
+
+// getSharedContact finds the user of a contact card, users who blocked the
+// sender cannot be shared by them
+func getSharedContact(ctx context.Context, queries *database.Queries, sender, recipient database.User, c *createContactData) (pgtype.Int4, error) {
+	if c == nil {
+		return pgtype.Int4{}, nil
+	}
+	contact, err := queries.GetUserByUuid(ctx, c.UserId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pgtype.Int4{}, errContactNotFound
+	} else if err != nil {
+		return pgtype.Int4{}, err
+	}
+	_, err = visibleToCaller(ctx, queries, sender, contact)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pgtype.Int4{}, errContactNotFound
+	} else if err != nil {
+		return pgtype.Int4{}, err
+	}
+	err = checkCanShareContact(ctx, queries, recipient.PvtID, contact.PvtID)
+	if err != nil {
+		return pgtype.Int4{}, err
+	}
+	return pgtype.Int4{Int32: contact.PvtID, Valid: true}, nil
+}
+
+// checkCanShareContact refuses a contact card when the recipient and the
+// contact blocked each other in either direction, like a block towards the
+// sender it is reported as not found
+func checkCanShareContact(ctx context.Context, queries *database.Queries, recipientPvtId, contactPvtId int32) error {
+	for _, pair := range [][2]int32{{recipientPvtId, contactPvtId}, {contactPvtId, recipientPvtId}} {
+		blocked, err := queries.IsBlockedBy(ctx, database.IsBlockedByParams{
+			BlockerPvtID: pair[0],
+			BlockedPvtID: pair[1],
+		})
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return errContactNotFound
+		}
+	}
+	return nil
+}
+
+// insertPayload stores the structured part of a location or contact card
+// message next to its text
+func insertPayload(ctx context.Context, queries *database.Queries, mssgId int64, m newMessage, now time.Time) error {
+	if m.Location != nil {
+		err := queries.CreateMessageLocation(ctx, database.CreateMessageLocationParams{
+			MssgID:         mssgId,
+			Latitude:       m.Location.Latitude,
+			Longitude:      m.Location.Longitude,
+			AccuracyMeters: m.Location.AccuracyMeters,
+			LiveUntil:      m.Location.LiveUntil,
+			UpdatedAt:      now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if m.ContactPvtID.Valid {
+		err := queries.CreateMessageContact(ctx, database.CreateMessageContactParams{
+			MssgID:       mssgId,
+			ContactPvtID: m.ContactPvtID.Int32,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type PublicLocation struct {
+	Latitude       float64    `json:"latitude"`
+	Longitude      float64    `json:"longitude"`
+	AccuracyMeters *float64   `json:"accuracy_meters,omitempty"`
+	LiveUntil      *time.Time `json:"live_until,omitempty"`
+	Live           bool       `json:"live"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// PublicContactCard shows the shared user as they are now
+type PublicContactCard struct {
+	UserID      pgtype.UUID `json:"user_id"`
+	Username    string      `json:"username"`
+	DisplayName string      `json:"display_name"`
+}
+
+func convertToPublicLocation(m database.MessagePublic) *PublicLocation {
+	if !m.LocationLatitude.Valid {
+		return nil
+	}
+	location := PublicLocation{
+		Latitude:  m.LocationLatitude.Float64,
+		Longitude: m.LocationLongitude.Float64,
+		UpdatedAt: m.LocationUpdatedAt.Time,
+	}
+	if m.LocationAccuracyMeters.Valid {
+		location.AccuracyMeters = &m.LocationAccuracyMeters.Float64
+	}
+	if m.LocationLiveUntil.Valid {
+		location.LiveUntil = &m.LocationLiveUntil.Time
+		location.Live = m.LocationLiveUntil.Time.After(time.Now().UTC())
+	}
+	return &location
+}
+
+func convertToPublicContactCard(m database.MessagePublic) *PublicContactCard {
+	if !m.ContactUserID.Valid {
+		return nil
+	}
+	return &PublicContactCard{
+		UserID:      m.ContactUserID,
+		Username:    m.ContactUsername.String,
+		DisplayName: m.ContactDisplayName.String,
+	}
+}
+
+// getLiveLocationMessage loads a location message the caller sent, only the
+// sender moves or stops a live location
+func getLiveLocationMessage(w http.ResponseWriter, r *http.Request, queries *database.Queries, user database.User) (database.MessagePublic, bool) {
+	m, err := getParticipantMessage(r, queries, user)
+	if err != nil || convertToPublicMessage(m, user).Expired {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return m, false
+	}
+	if m.MssgType != database.MessageTypeLocation {
+		render.RespondFailure(w, http.StatusBadRequest, "message is not a location")
+		return m, false
+	}
+	if m.FromUserID != user.UserID {
+		render.RespondFailure(w, http.StatusForbidden, notLocationSenderError)
+		return m, false
+	}
+	return m, true
+}
+
+type updateLocationData struct {
+	Latitude       *float64 `json:"latitude"        validate:"required,min=-90,max=90"`
+	Longitude      *float64 `json:"longitude"       validate:"required,min=-180,max=180"`
+	AccuracyMeters float64  `json:"accuracy_meters" validate:"omitempty,gt=0,max=100000"`
+}
+
+// handleUpdateLiveLocation moves a live location, both participants get
+// the message again with the new position
+func handleUpdateLiveLocation(w http.ResponseWriter, r *http.Request) {
+	data := updateLocationData{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&data)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(data)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	queries := database.New(apiCfg.ConnPool)
+	m, ok := getLiveLocationMessage(w, r, queries, user)
+	if !ok {
+		return
+	}
+	slog.Info("updating live location", "user_id", user.UserID, "mssg_id", m.MssgID)
+
+	updated, err := queries.UpdateLiveLocation(r.Context(), database.UpdateLiveLocationParams{
+		Latitude:       *data.Latitude,
+		Longitude:      *data.Longitude,
+		AccuracyMeters: pgtype.Float8{Float64: data.AccuracyMeters, Valid: data.AccuracyMeters != 0},
+		UpdatedAt:      time.Now().UTC(),
+		MssgID:         m.MssgID,
+	})
+	if err != nil {
+		slog.Error("could not update live location", "mssg_id", m.MssgID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	if updated == 0 {
+		render.RespondFailure(w, http.StatusConflict, liveLocationEndedError)
+		return
+	}
+	respondMessageChange(w, r, queries, user, m.MssgID, true)
+}
+
+// handleStopLiveLocation ends a live location early, the last position
+// stays in the conversation
+func handleStopLiveLocation(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	m, ok := getLiveLocationMessage(w, r, queries, user)
+	if !ok {
+		return
+	}
+	slog.Info("stopping live location", "user_id", user.UserID, "mssg_id", m.MssgID)
+
+	stopped, err := queries.StopLiveLocation(r.Context(), database.StopLiveLocationParams{
+		Now:    timestampNow(),
+		MssgID: m.MssgID,
+	})
+	if err != nil {
+		slog.Error("could not stop live location", "mssg_id", m.MssgID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	respondMessageChange(w, r, queries, user, m.MssgID, stopped != 0)
+}
@@ -1,32 +1,51 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/Suryarpan/chat-api/internal/apiconf"
 	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/blobstore"
+	"github.com/Suryarpan/chat-api/internal/linkpreview"
+	"github.com/Suryarpan/chat-api/internal/presence"
+	"github.com/Suryarpan/chat-api/internal/realtime"
+	"github.com/Suryarpan/chat-api/internal/webhook"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func setUpMiddlewares(r *chi.Mux, cp *pgxpool.Pool) error {
+const apiV1Prefix = "/api/v1"
+
+func setUpMiddlewares(r *chi.Mux, apiCfg apiconf.ApiConfig) error {
 	if r == nil {
 		return errors.New("please provide a router")
-	} else if cp == nil {
+	} else if apiCfg.ConnPool == nil {
 		return errors.New("please provide a connection pool")
+	} else if apiCfg.Blobs == nil {
+		return errors.New("please provide a blob store")
+	} else if apiCfg.Hub == nil || apiCfg.Presence == nil {
+		return errors.New("please provide the real-time services")
+	} else if apiCfg.Previews == nil {
+		return errors.New("please provide a link preview fetcher")
+	} else if apiCfg.Webhooks == nil {
+		return errors.New("please provide a webhook sender")
 	}
 
 	r.Use(apiconf.Logger)
-	r.Use(apiconf.ApiConfigure(cp))
+	r.Use(apiconf.ApiConfigure(apiCfg))
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.CleanPath)
-	r.Use(middleware.AllowContentType("application/json", "text/xml"))
+	r.Use(middleware.AllowContentType("application/json", "text/xml", "multipart/form-data"))
 	r.Use(middleware.ContentCharset("", "UTF-8", "Latin-1"))
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
@@ -50,6 +69,10 @@ func routeSetup(r *chi.Mux) error {
 	r.Mount("/user", UserRouter())
 	// chat data setup
 	r.With(auth.Authentication).Mount("/message", MessageRouter())
+	// real-time setup
+	r.With(auth.Authentication).Mount("/events", EventRouter())
+	// webhook setup
+	r.With(auth.Authentication).Mount("/webhooks", WebhookRouter())
 	// admin setup
 	return nil
 }
@@ -57,6 +80,12 @@ func routeSetup(r *chi.Mux) error {
 func main() {
 	logger := slog.New(*apiconf.LoggerConfig())
 	slog.SetDefault(logger)
+	// request types using the custom validation tags, checked before
+	// anything starts
+	err := apiconf.CheckTags(createMessageData{}, batchMessageData{}, saveDraftData{}, updateUserData{})
+	if err != nil {
+		panic(fmt.Sprintf("Error: could not check validation tags: %v", err))
+	}
 	// DB Setup
 	connPool, err := apiconf.SetupPool()
 	if err != nil {
@@ -68,11 +97,58 @@ func main() {
 	if err != nil {
 		panic(fmt.Sprintf("Error: could not setup auth: %v", err))
 	}
+	// Blob store setup
+	blobStore, err := blobstore.SetupStore()
+	if err != nil {
+		panic(fmt.Sprintf("Error: could not setup blob store: %v", err))
+	}
+	// Real-time setup
+	hub, err := realtime.NewHub(connPool)
+	if err != nil {
+		panic(fmt.Sprintf("Error: could not setup real-time hub: %v", err))
+	}
+	presenceRegistry, err := presence.NewRegistry(connPool)
+	if err != nil {
+		panic(fmt.Sprintf("Error: could not setup presence: %v", err))
+	}
+	// Webhook setup
+	webhookSender, err := webhook.SetupSender()
+	if err != nil {
+		panic(fmt.Sprintf("Error: could not setup webhooks: %v", err))
+	}
+	apiCfg := apiconf.ApiConfig{
+		ConnPool: connPool,
+		Blobs:    blobStore,
+		Hub:      hub,
+		Presence: presenceRegistry,
+		Previews: linkpreview.NewHTTPFetcher(),
+		Webhooks: webhookSender,
+	}
+	// Background workers stop on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	workers := sync.WaitGroup{}
+	startWorker := func(run func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(ctx)
+		}()
+	}
+	startWorker(hub.Run)
+	startWorker(presenceRegistry.Run)
+	startWorker(func(ctx context.Context) { runScheduler(ctx, apiCfg) })
+	startWorker(func(ctx context.Context) { runPurger(ctx, apiCfg) })
+	startWorker(func(ctx context.Context) { runIdempotencyPruner(ctx, apiCfg) })
+	startWorker(func(ctx context.Context) { runUnfurler(ctx, apiCfg) })
+	startWorker(func(ctx context.Context) { runWebhookDispatcher(ctx, apiCfg) })
+	startWorker(func(ctx context.Context) { runWebhookLogPruner(ctx, apiCfg) })
+	startWorker(func(ctx context.Context) { runStoredEventPruner(ctx, apiCfg) })
 
 	// router setup
 	mainRouter := chi.NewRouter()
 
-	err = setUpMiddlewares(mainRouter, connPool)
+	err = setUpMiddlewares(mainRouter, apiCfg)
 	if err != nil {
 		panic(fmt.Sprintf("Error: could not setup middlewares: %v", err))
 	}
@@ -87,7 +163,7 @@ func main() {
 		panic(fmt.Sprintf("Error: could not mount the sub routes: %v", err))
 	}
 	// router run
-	mainRouter.Mount("/api/v1", apiV1router)
+	mainRouter.Mount(apiV1Prefix, apiV1router)
 	port, ok := os.LookupEnv("CHAT_API_PORT")
 	if !ok {
 		panic("Error: could not find CHAT_API_PORT environment variable")
@@ -98,8 +174,19 @@ func main() {
 		Handler: mainRouter,
 		Addr:    ":" + port,
 	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err := server.Shutdown(shutdownCtx)
+		if err != nil {
+			slog.Error("could not shutdown server", "error", err)
+		}
+	}()
 	err = server.ListenAndServe()
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		slog.Error(fmt.Sprintf("%v", err))
 	}
+	stop()
+	workers.Wait()
 }
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	minUsernameLength  = 5
+	maxUsernameLength  = 50
+	maxMessageMentions = 50
+)
+
+// mentionPattern finds an @username at the start of the body or after a
+// character that cannot be part of a word, so email addresses are skipped
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@])@([\p{L}\p{N}_.\-]+)`)
+
+// PublicMention marks where a user is mentioned in the message body. Offset
+// and length count unicode code points and cover the @username as it was
+// typed, the username is the current one so clients can render renames.
+type PublicMention struct {
+	UserID   pgtype.UUID `json:"user_id"`
+	Username string      `json:"username"`
+	Offset   int32       `json:"offset"`
+	Length   int32       `json:"length"`
+}
+
+// mentionCandidate is an @username in a body that may not name anybody
+type mentionCandidate struct {
+	username string
+	offset   int32
+	length   int32
+}
+
+func parseMentions(body string) []mentionCandidate {
+	var candidates []mentionCandidate
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(body, -1) {
+		// trailing dots and dashes are more likely punctuation than username
+		username := strings.TrimRight(body[match[2]:match[3]], ".-")
+		n := utf8.RuneCountInString(username)
+		if n < minUsernameLength || n > maxUsernameLength {
+			continue
+		}
+		candidates = append(candidates, mentionCandidate{
+			username: username,
+			offset:   int32(utf8.RuneCountInString(body[:match[2]-1])),
+			length:   int32(n + 1),
+		})
+		if len(candidates) == maxMessageMentions {
+			break
+		}
+	}
+	return candidates
+}
+
+// matchMentions keeps the candidates naming the given participant, only
+// people who can read the message can be mentioned in it
+func matchMentions(mssgId int64, candidates []mentionCandidate, participant database.User) []database.CopyMessageMentionsParams {
+	var mentions []database.CopyMessageMentionsParams
+	for _, c := range candidates {
+		if c.username != participant.Username {
+			continue
+		}
+		mentions = append(mentions, database.CopyMessageMentionsParams{
+			MssgID:         mssgId,
+			MentionedPvtID: participant.PvtID,
+			StartOffset:    c.offset,
+			Length:         c.length,
+		})
+	}
+	return mentions
+}
+
+// encodeMentions builds the mentions column of a message that was not read
+// back from the database
+func encodeMentions(mentions []database.CopyMessageMentionsParams, participant database.User) ([]byte, error) {
+	public := make([]PublicMention, 0, len(mentions))
+	for _, m := range mentions {
+		public = append(public, PublicMention{
+			UserID:   participant.UserID,
+			Username: participant.Username,
+			Offset:   m.StartOffset,
+			Length:   m.Length,
+		})
+	}
+	return json.Marshal(public)
+}
+
+// insertMentions stores the mentions of a new message, senders do not
+// mention themselves
+func insertMentions(ctx context.Context, queries *database.Queries, mssgId int64, m newMessage) error {
+	candidates := parseMentions(m.MssgBody)
+	if len(candidates) == 0 || m.FromPvtID == m.ToPvtID {
+		return nil
+	}
+	toUser, err := queries.GetUserById(ctx, m.ToPvtID)
+	if err != nil {
+		return err
+	}
+	mentions := matchMentions(mssgId, candidates, toUser)
+	if len(mentions) == 0 {
+		return nil
+	}
+	_, err = queries.CopyMessageMentions(ctx, mentions)
+	return err
+}
+
+// handleListMentions is the feed of messages mentioning the caller, latest
+// message first
+func handleListMentions(w http.ResponseWriter, r *http.Request) {
+	page, err := getPageParams(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid pagination parameters")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(page)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("listing mentions", "user_id", user.UserID)
+
+	queries := database.New(apiCfg.ConnPool)
+	rows, err := queries.ListMentionedMessages(r.Context(), database.ListMentionedMessagesParams{
+		MentionedPvtID: user.PvtID,
+		Now:            timestampNow(),
+		PageLimit:      page.Limit,
+		PageOffset:     page.Offset,
+	})
+	if err != nil {
+		slog.Error("could not list mentions", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	messages := make([]PublicMessage, 0, len(rows))
+	for _, m := range rows {
+		messages = append(messages, convertToPublicMessage(database.MessagePublic(m), user))
+	}
+	err = setStarred(r, queries, messages, user)
+	if err != nil {
+		slog.Error("could not list stars", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, newPagedResponse(messages, page))
+}
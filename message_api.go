@@ -1,29 +1,425 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/Suryarpan/chat-api/internal/apiconf"
 	"github.com/Suryarpan/chat-api/internal/auth"
 	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/internal/realtime"
+	"github.com/Suryarpan/chat-api/internal/richtext"
 	"github.com/Suryarpan/chat-api/render"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const (
 	insufficientStorageMessageError = "could not create message at this moment"
+	messageNotFoundError            = "could not find message"
+	messageEvent                    = "message"
 )
 
+type PublicMessage struct {
+	MssgID        int64                  `json:"mssg_id"`
+	FromUserID    pgtype.UUID            `json:"from_user_id"`
+	ToUserID      pgtype.UUID            `json:"to_user_id"`
+	MssgStatus    database.MessageStatus `json:"mssg_status"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+	MssgType      database.MessageType   `json:"mssg_type"`
+	AttachMssgID  pgtype.Int8            `json:"attach_mssg_id"`
+	MssgBody      string                 `json:"mssg_body"`
+	Format        database.MessageFormat `json:"format"`
+	MssgHTML      string                 `json:"mssg_html,omitempty"`
+	Mentions      []PublicMention        `json:"mentions,omitempty"`
+	Attachment    *PublicAttachment      `json:"attachment,omitempty"`
+	LinkPreview   *PublicLinkPreview     `json:"link_preview,omitempty"`
+	Poll          *PublicPoll            `json:"poll,omitempty"`
+	Location      *PublicLocation        `json:"location,omitempty"`
+	Contact       *PublicContactCard     `json:"contact,omitempty"`
+	Thread        *PublicThreadSummary   `json:"thread,omitempty"`
+	ExpiresAt     *time.Time             `json:"expires_at,omitempty"`
+	Expired       bool                   `json:"expired,omitempty"`
+	ForwardedFrom *pgtype.UUID           `json:"forwarded_from,omitempty"`
+	Pinned        bool                   `json:"pinned"`
+	Starred       *bool                  `json:"starred,omitempty"`
+}
+
+// convertToPublicMessage prepares a message for the viewer. The read status
+// is only revealed to the sender if the receiver sends read receipts,
+// otherwise the message looks as it did before the read: delivery is not
+// recorded, so it is still sent, and reading is the only change that moves
+// updated_at past created_at. An expired message is a tombstone even before the purger got to it. Stars are
+// private, Starred is left out unless the caller looked up the viewer's star.
+// Thread summaries are set by the callers listing threads.
+func convertToPublicMessage(m database.MessagePublic, viewer database.User) PublicMessage {
+	status, updatedAt := m.MssgStatus, m.UpdatedAt
+	if viewer.UserID != m.ToUserID && !m.ReadReceipts && status == database.MessageStatusRead {
+		status, updatedAt = database.MessageStatusSent, m.CreatedAt
+	}
+	public := PublicMessage{
+		MssgID:       m.MssgID,
+		FromUserID:   m.FromUserID,
+		ToUserID:     m.ToUserID,
+		MssgStatus:   status,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    updatedAt,
+		MssgType:     m.MssgType,
+		AttachMssgID: m.AttachMssgID,
+		MssgBody:     m.MssgBody,
+		Format:       m.MssgFormat,
+		MssgHTML:     m.MssgHtml.String,
+		Pinned:       m.Pinned,
+		LinkPreview:  convertToPublicLinkPreview(m),
+		Poll:         convertToPublicPoll(m),
+		Location:     convertToPublicLocation(m),
+		Contact:      convertToPublicContactCard(m),
+	}
+	if m.AttachmentID.Valid {
+		public.Attachment = &PublicAttachment{
+			AttachmentID: m.AttachmentID,
+			FileName:     m.FileName.String,
+			MimeType:     m.MimeType.String,
+			SizeBytes:    m.SizeBytes.Int64,
+			Checksum:     m.Checksum.String,
+			Width:        m.Width,
+			Height:       m.Height,
+			URL:          attachmentURL(m.AttachmentID),
+		}
+	}
+	if len(m.Mentions) != 0 {
+		err := json.Unmarshal(m.Mentions, &public.Mentions)
+		if err != nil {
+			slog.Warn("could not decode message mentions", "mssg_id", m.MssgID, "error", err)
+		}
+	}
+	if m.ForwardedFromUserID.Valid {
+		public.ForwardedFrom = &m.ForwardedFromUserID
+	}
+	if m.ExpiresAt.Valid {
+		public.ExpiresAt = &m.ExpiresAt.Time
+		if !m.ExpiresAt.Time.After(time.Now().UTC()) {
+			public.Expired = true
+			public.MssgBody = ""
+			public.MssgHTML = ""
+			public.Mentions = nil
+			public.Attachment = nil
+			public.LinkPreview = nil
+			public.Poll = nil
+			public.Location = nil
+			public.Contact = nil
+		}
+	}
+	return public
+}
+
+func isParticipant(m database.MessagePublic, user database.User) bool {
+	return user.UserID == m.FromUserID || user.UserID == m.ToUserID
+}
+
+// respondMessageChange sends back the message after a pin, vote or other
+// change, both sides get the update when something changed
+func respondMessageChange(w http.ResponseWriter, r *http.Request, queries *database.Queries, user database.User, mssgId int64, changed bool) {
+	m, err := queries.GetMessageByIdPublic(r.Context(), mssgId)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	}
+	if changed {
+		participants, err := queries.GetMessageById(r.Context(), mssgId)
+		if err == nil {
+			publishMessage(r.Context(), apiconf.GetConfig(r).Hub, m, participants.FromPvtID, participants.ToPvtID)
+		}
+	}
+	starred, err := queries.IsMessageStarred(r.Context(), database.IsMessageStarredParams{
+		OwnerPvtID: user.PvtID,
+		MssgID:     mssgId,
+	})
+	if err != nil {
+		slog.Error("could not check message star", "mssg_id", mssgId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	public := convertToPublicMessage(m, user)
+	public.Starred = &starred
+	err = setMyVotes(r.Context(), queries, &public, user)
+	if err != nil {
+		slog.Error("could not list poll votes", "mssg_id", mssgId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, public)
+}
+
+// getParticipantMessage loads the message in the url, messages of other
+// conversations are not found
+func getParticipantMessage(r *http.Request, queries *database.Queries, user database.User) (database.MessagePublic, error) {
+	mssgId, err := getMssgIdParam(r)
+	if err != nil {
+		return database.MessagePublic{}, pgx.ErrNoRows
+	}
+	m, err := queries.GetMessageByIdPublic(r.Context(), mssgId)
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+	if !isParticipant(m, user) {
+		return database.MessagePublic{}, pgx.ErrNoRows
+	}
+	return m, nil
+}
+
+var errContactsOnly = errors.New("user only accepts messages from contacts")
+
+// checkCanMessage applies the blocks and privacy settings of the recipient
+// to the sender. A blocked sender gets pgx.ErrNoRows, as if the recipient
+// did not exist.
+func checkCanMessage(ctx context.Context, queries *database.Queries, from, to database.User) error {
+	if from.PvtID == to.PvtID {
+		return nil
+	}
+	_, err := visibleToCaller(ctx, queries, from, to)
+	if err != nil {
+		return err
+	}
+	settings, err := getUserSettings(ctx, queries, to.PvtID)
+	if err != nil {
+		return err
+	}
+	if settings.AcceptMessagesFrom != database.MessagePrivacyContacts {
+		return nil
+	}
+	isContact, err := queries.IsContact(ctx, database.IsContactParams{
+		OwnerPvtID:   to.PvtID,
+		ContactPvtID: from.PvtID,
+	})
+	if err != nil {
+		return err
+	}
+	if !isContact {
+		return errContactsOnly
+	}
+	return nil
+}
+
+func respondCannotMessage(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		render.RespondFailure(w, http.StatusBadRequest, "could not find user to send to")
+	case errors.Is(err, errContactsOnly):
+		render.RespondFailure(w, http.StatusForbidden, errContactsOnly.Error())
+	default:
+		slog.Error("could not check recipient privacy", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+	}
+}
+
+// createMessageData describes a new message, the body of an attachment,
+// location or contact card is an optional caption. A message with send_at is
+// scheduled instead of sent. The body is plain text unless format asks for
+// markdown. The body of a poll is its question. Polls, locations and contact
+// cards are sent right away.
 type createMessageData struct {
-	ToUserId     pgtype.UUID `json:"to_user_id"     validate:"required"`
-	MssgType     string      `json:"mssg_type"      validate:"required,oneof=normal reply reaction"`
-	AttachMssgId int64       `json:"attach_mssg_id" validate:"omitempty,min=1"`
-	MssgBody     string      `json:"mssg_body"      validate:"required,min=1,printascii|alphanumunicode"`
+	ToUserId     pgtype.UUID         `json:"to_user_id"     validate:"required"`
+	MssgType     string              `json:"mssg_type"      validate:"required,oneof=normal reply reaction attachment poll location contact"`
+	AttachMssgId int64               `json:"attach_mssg_id" validate:"omitempty,min=1"`
+	AttachmentId pgtype.UUID         `json:"attachment_id"  validate:"required_if=MssgType attachment,excluded_unless=MssgType attachment"`
+	MssgBody     string              `json:"mssg_body"      validate:"required_if=MssgType normal,required_if=MssgType reply,required_if=MssgType reaction,required_if=MssgType poll,maxgraphemes=4096"`
+	Format       string              `json:"format"         validate:"omitempty,oneof=plain markdown"`
+	Poll         *createPollData     `json:"poll"           validate:"required_if=MssgType poll,excluded_unless=MssgType poll"`
+	Location     *createLocationData `json:"location"       validate:"required_if=MssgType location,excluded_unless=MssgType location"`
+	Contact      *createContactData  `json:"contact"        validate:"required_if=MssgType contact,excluded_unless=MssgType contact"`
+	SendAt       *time.Time          `json:"send_at"        validate:"omitnil,excluded_if=MssgType poll,excluded_if=MssgType location,excluded_if=MssgType contact,gt"`
+	TtlSeconds   int32               `json:"ttl_seconds"    validate:"omitempty,min=5,max=7776000"`
+	ClientMssgId string              `json:"client_mssg_id" validate:"omitempty,max=128,printascii"`
+}
+
+var (
+	errAttachmentNotFound  = errors.New("could not find attachment to send")
+	errReplyTargetNotFound = errors.New("could not find message to reply to")
+)
+
+// newMessage holds everything needed to write a message
+type newMessage struct {
+	FromPvtID    int32
+	ToPvtID      int32
+	MssgType     database.MessageType
+	AttachMssgID pgtype.Int8
+	AttachmentID pgtype.UUID
+	MssgBody     string
+	Format       database.MessageFormat
+	Poll         *newPoll
+	Location     *newLocation
+	ContactPvtID pgtype.Int4
+	// TtlSeconds overrides the conversation's disappearing messages setting
+	TtlSeconds int32
+	// ForwardedFromPvtID is the original author of a forwarded message
+	ForwardedFromPvtID pgtype.Int4
+}
+
+// messageFormat maps the optional format field, plain text is the default
+func messageFormat(format string) database.MessageFormat {
+	if format == "" {
+		return database.MessageFormatPlain
+	}
+	return database.MessageFormat(format)
+}
+
+// renderBody is the sanitized html stored next to a markdown body, plain
+// bodies have none
+func renderBody(body string, format database.MessageFormat) (pgtype.Text, error) {
+	if format != database.MessageFormatMarkdown || body == "" {
+		return pgtype.Text{}, nil
+	}
+	html, err := richtext.RenderMarkdown(body)
+	if err != nil {
+		return pgtype.Text{}, err
+	}
+	return pgtype.Text{String: html, Valid: true}, nil
+}
+
+// checkReplyTarget makes sure a message only points at a message of its own
+// conversation, the same rule batches follow per item
+func checkReplyTarget(ctx context.Context, queries *database.Queries, m newMessage) error {
+	if !m.AttachMssgID.Valid {
+		return nil
+	}
+	found, err := queries.ListConversationMessageIds(ctx, database.ListConversationMessageIdsParams{
+		MssgIds:           []int64{m.AttachMssgID.Int64},
+		CounterpartPvtIds: []int32{m.ToPvtID},
+		UserPvtID:         m.FromPvtID,
+	})
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		return errReplyTargetNotFound
+	}
+	return nil
+}
+
+// insertMessage writes the parts of a message with the given queries, the
+// caller owns the transaction around it
+func insertMessage(ctx context.Context, queries *database.Queries, m newMessage) (database.MessagePublic, error) {
+	err := checkReplyTarget(ctx, queries, m)
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+	ttl := m.TtlSeconds
+	if ttl == 0 {
+		convTtl, err := queries.GetConversationTTL(ctx, database.GetConversationTTLParams{
+			UserPvtID:  m.FromPvtID,
+			OtherPvtID: m.ToPvtID,
+		})
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return database.MessagePublic{}, err
+		}
+		ttl = convTtl.TtlSeconds
+	}
+
+	slog.Debug("creating message meta entry")
+	now := time.Now().UTC()
+	expiresAt := pgtype.Timestamp{}
+	if ttl != 0 {
+		expiresAt = pgtype.Timestamp{Time: now.Add(time.Duration(ttl) * time.Second), Valid: true}
+	}
+	mssgMeta, err := queries.CreateMessage(ctx, database.CreateMessageParams{
+		FromPvtID:          m.FromPvtID,
+		ToPvtID:            m.ToPvtID,
+		MssgStatus:         database.MessageStatusSent,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+		ExpiresAt:          expiresAt,
+		ForwardedFromPvtID: m.ForwardedFromPvtID,
+	})
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+
+	slog.Debug("creating type information entry of message", "mssg id", mssgMeta.MssgID)
+	_, err = queries.CreateMessageType(ctx, database.CreateMessageTypeParams{
+		MssgID:       mssgMeta.MssgID,
+		MssgType:     m.MssgType,
+		AttachMssgID: m.AttachMssgID,
+	})
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+
+	if m.AttachmentID.Valid {
+		slog.Debug("attaching uploaded file to message", "mssg id", mssgMeta.MssgID)
+		_, err = queries.AttachToMessage(ctx, database.AttachToMessageParams{
+			MssgID:       pgtype.Int8{Int64: mssgMeta.MssgID, Valid: true},
+			AttachmentID: m.AttachmentID,
+			OwnerPvtID:   m.FromPvtID,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return database.MessagePublic{}, errAttachmentNotFound
+		} else if err != nil {
+			return database.MessagePublic{}, err
+		}
+	}
+
+	slog.Debug("creating body entry of message", "mssg id", mssgMeta.MssgID)
+	mssgHtml, err := renderBody(m.MssgBody, m.Format)
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+	_, err = queries.CreateMessageText(ctx, database.CreateMessageTextParams{
+		MssgID:     mssgMeta.MssgID,
+		MssgBody:   m.MssgBody,
+		MssgFormat: m.Format,
+		MssgHtml:   mssgHtml,
+	})
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+	err = insertMentions(ctx, queries, mssgMeta.MssgID, m)
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+	err = queueLinkPreview(ctx, queries, mssgMeta.MssgID, m.MssgBody, now)
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+	if m.Poll != nil {
+		err = insertPoll(ctx, queries, mssgMeta.MssgID, *m.Poll)
+		if err != nil {
+			return database.MessagePublic{}, err
+		}
+	}
+	err = insertPayload(ctx, queries, mssgMeta.MssgID, m, now)
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+
+	slog.Debug("fetching public data from database", "mssg_id", mssgMeta.MssgID)
+	return queries.GetMessageByIdPublic(ctx, mssgMeta.MssgID)
+}
+
+// sentMessage is a written message waiting to be published after commit
+type sentMessage struct {
+	content            database.MessagePublic
+	fromPvtId, toPvtId int32
+}
+
+// publishMessage pushes a new or changed message to both participants, each
+// sees it the way convertToPublicMessage shows it to them
+func publishMessage(ctx context.Context, hub *realtime.Hub, m database.MessagePublic, fromPvtId, toPvtId int32) {
+	err := hub.Publish(ctx, []int32{toPvtId}, messageEvent, convertToPublicMessage(m, database.User{UserID: m.ToUserID}))
+	if err == nil && fromPvtId != toPvtId {
+		err = hub.Publish(ctx, []int32{fromPvtId}, messageEvent, convertToPublicMessage(m, database.User{UserID: m.FromUserID}))
+	}
+	if err != nil {
+		slog.Warn("could not publish message", "mssg_id", m.MssgID, "error", err)
+	}
 }
 
 func handleCreateMessage(w http.ResponseWriter, r *http.Request) {
@@ -35,6 +431,16 @@ func handleCreateMessage(w http.ResponseWriter, r *http.Request) {
 		render.RespondFailure(w, 400, "could not decode data")
 		return
 	}
+	// the header and the body field are two ways to send the same key
+	if key := r.Header.Get(idempotencyKeyHeader); key != "" {
+		if data.ClientMssgId != "" && data.ClientMssgId != key {
+			render.RespondFailure(w, http.StatusBadRequest, "client_mssg_id does not match the Idempotency-Key header")
+			return
+		}
+		data.ClientMssgId = key
+	}
+	data.MssgBody = richtext.Clean(data.MssgBody)
+	cleanPollOptions(data.Poll)
 
 	apiCfg := apiconf.GetConfig(r)
 	// validate incoming data
@@ -47,7 +453,7 @@ func handleCreateMessage(w http.ResponseWriter, r *http.Request) {
 			slog.Error("error with validator definition", "error", err)
 			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
 		} else {
-			render.RespondValidationFailure(w, validationErrors)
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
 		}
 		return
 	}
@@ -62,81 +468,281 @@ func handleCreateMessage(w http.ResponseWriter, r *http.Request) {
 	}
 	slog.Debug("received user data", "to user", toUser)
 
+	slog.Info("checking recipient privacy settings")
+	err = checkCanMessage(r.Context(), queries, fromUser, toUser)
+	if err != nil {
+		respondCannotMessage(w, err)
+		return
+	}
+	contactPvtId, err := getSharedContact(r.Context(), queries, fromUser, toUser, data.Contact)
+	if errors.Is(err, errContactNotFound) {
+		render.RespondFailure(w, http.StatusBadRequest, errContactNotFound.Error())
+		return
+	} else if err != nil {
+		slog.Error("could not find shared contact", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+
+	m := newMessage{
+		FromPvtID: fromUser.PvtID,
+		ToPvtID:   toUser.PvtID,
+		MssgType:  database.MessageType(data.MssgType),
+		AttachMssgID: pgtype.Int8{
+			Int64: data.AttachMssgId,
+			Valid: data.AttachMssgId != 0,
+		},
+		AttachmentID: data.AttachmentId,
+		MssgBody:     data.MssgBody,
+		Format:       messageFormat(data.Format),
+		Poll:         convertToNewPoll(data.Poll),
+		Location:     convertToNewLocation(data.Location, time.Now().UTC()),
+		ContactPvtID: contactPvtId,
+		TtlSeconds:   data.TtlSeconds,
+	}
+	requestHash, err := hashCreateMessage(data)
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+
 	c, err := apiCfg.ConnPool.Acquire(r.Context())
-	defer c.Release()
 	if err != nil {
 		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
 		return
 	}
+	defer c.Release()
 
 	slog.Info("starting transaction")
 	tx, err := c.Begin(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
 	defer tx.Rollback(r.Context())
+	txQueries := queries.WithTx(tx)
+
+	if data.ClientMssgId != "" {
+		original, err := claimIdempotencyKey(r.Context(), txQueries, fromUser.PvtID, data.ClientMssgId, requestHash)
+		if errors.Is(err, errIdempotencyKeyReused) {
+			render.RespondFailure(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		} else if errors.Is(err, errIdempotencyInProgress) {
+			render.RespondFailure(w, http.StatusConflict, err.Error())
+			return
+		} else if err != nil {
+			slog.Error("could not claim idempotency key", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+			return
+		}
+		if original != nil {
+			respondReplay(w, r, txQueries, *original, fromUser, toUser)
+			return
+		}
+	}
+
+	// the draft became this message, other devices drop it too
+	draft, err := clearDraftOnSend(r.Context(), txQueries, fromUser.PvtID, toUser.PvtID)
 	if err != nil {
+		slog.Error("could not clear draft", "error", err)
 		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
 		return
 	}
 
-	slog.Debug("creating message meta entry")
-	txQuery := queries.WithTx(tx)
-	mssgMeta, err := txQuery.CreateMessage(r.Context(), database.CreateMessageParams{
-		FromPvtID:  fromUser.PvtID,
-		ToPvtID:    toUser.PvtID,
-		MssgStatus: database.MessageStatusSent,
-		CreatedAt:  time.Now().UTC(),
-		UpdatedAt:  time.Now().UTC(),
-	})
+	if data.SendAt != nil {
+		sm, err := scheduleMessage(r.Context(), txQueries, m, *data.SendAt)
+		if errors.Is(err, errAttachmentNotFound) || errors.Is(err, errReplyTargetNotFound) {
+			render.RespondFailure(w, http.StatusBadRequest, err.Error())
+			return
+		} else if err != nil {
+			slog.Error("could not schedule message", "error", err)
+			render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
+			return
+		}
+		err = recordIdempotencyResult(r.Context(), txQueries, fromUser.PvtID, data.ClientMssgId, pgtype.Int8{}, pgtype.Int8{Int64: sm.ScheduleID, Valid: true})
+		if err == nil {
+			err = tx.Commit(r.Context())
+		}
+		if err != nil {
+			render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
+			return
+		}
+		if draft != nil {
+			publishDraft(r.Context(), apiCfg.Hub, *draft, toUser.UserID)
+		}
+		render.RespondSuccess(w, http.StatusAccepted, convertToPublicScheduled(sm, toUser.UserID))
+		return
+	}
+
+	mssgContent, err := insertMessage(r.Context(), txQueries, m)
+	if errors.Is(err, errAttachmentNotFound) || errors.Is(err, errReplyTargetNotFound) {
+		render.RespondFailure(w, http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		slog.Error("could not create message", "error", err)
+		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
+		return
+	}
+	err = recordIdempotencyResult(r.Context(), txQueries, fromUser.PvtID, data.ClientMssgId, pgtype.Int8{Int64: mssgContent.MssgID, Valid: true}, pgtype.Int8{})
 	if err != nil {
+		slog.Error("could not record idempotency key", "error", err)
+		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
+		return
+	}
+	sent := []sentMessage{{mssgContent, fromUser.PvtID, toUser.PvtID}}
+	err = queueMessageWebhooks(r.Context(), txQueries, sent)
+	if err != nil {
+		slog.Error("could not queue message webhooks", "mssg_id", mssgContent.MssgID, "error", err)
 		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
 		return
 	}
 
-	slog.Debug("creating type information entry of message", "mssg id", mssgMeta.MssgID)
-	_, err = txQuery.CreateMessageType(r.Context(), database.CreateMessageTypeParams{
-		MssgID:   mssgMeta.MssgID,
-		MssgType: database.MessageType(data.MssgType),
-		AttachMssgID: pgtype.Int8{
-			Int64: data.AttachMssgId,
-			Valid: data.AttachMssgId != 0,
-		},
-	})
+	slog.Debug("commiting the db writes", "mssg_id", mssgContent.MssgID)
+	err = tx.Commit(r.Context())
 	if err != nil {
 		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
 		return
 	}
+	publishMessage(r.Context(), apiCfg.Hub, mssgContent, fromUser.PvtID, toUser.PvtID)
+	notifyRecipients(r.Context(), apiCfg, sent)
+	if draft != nil {
+		publishDraft(r.Context(), apiCfg.Hub, *draft, toUser.UserID)
+	}
 
-	slog.Debug("creating body entry of message", "mssg id", mssgMeta.MssgID)
-	_, err = txQuery.CreateMessageText(r.Context(), database.CreateMessageTextParams{
-		MssgID:   mssgMeta.MssgID,
-		MssgBody: data.MssgBody,
+	slog.Info("sending back reponse", "message", mssgContent)
+	render.RespondSuccess(w, http.StatusOK, convertToPublicMessage(mssgContent, fromUser))
+}
+
+func getMssgIdParam(r *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, "mssg_id"), 10, 64)
+}
+
+func handleGetMessage(w http.ResponseWriter, r *http.Request) {
+	mssgId, err := getMssgIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid message id")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("fetching message", "user_id", user.UserID, "mssg_id", mssgId)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	mssgContent, err := queries.GetMessageByIdPublic(r.Context(), mssgId)
+	if err != nil || !isParticipant(mssgContent, user) {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	}
+	starred, err := queries.IsMessageStarred(r.Context(), database.IsMessageStarredParams{
+		OwnerPvtID: user.PvtID,
+		MssgID:     mssgId,
 	})
 	if err != nil {
-		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
+		slog.Error("could not check message star", "mssg_id", mssgId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
 		return
 	}
+	public := convertToPublicMessage(mssgContent, user)
+	public.Starred = &starred
+	err = setMyVotes(r.Context(), queries, &public, user)
+	if err != nil {
+		slog.Error("could not list poll votes", "mssg_id", mssgId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, public)
+}
 
-	slog.Debug("fetching public data from database", "mssg_id", mssgMeta.MssgID)
-	mssgContent, err := txQuery.GetMessageByIdPublic(r.Context(), mssgMeta.MssgID)
+func handleReadMessage(w http.ResponseWriter, r *http.Request) {
+	mssgId, err := getMssgIdParam(r)
 	if err != nil {
-		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
+		render.RespondFailure(w, http.StatusBadRequest, "invalid message id")
 		return
 	}
+	user := auth.GetUserData(r)
+	slog.Info("marking message as read", "user_id", user.UserID, "mssg_id", mssgId)
 
-	slog.Debug("commiting the db writes", "mssg_id", mssgMeta.MssgID)
-	err = tx.Commit(r.Context())
+	apiCfg := apiconf.GetConfig(r)
+	c, err := apiCfg.ConnPool.Acquire(r.Context())
 	if err != nil {
-		render.RespondFailure(w, http.StatusInsufficientStorage, insufficientStorageMessageError)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	defer c.Release()
+	// the receipt and its webhook delivery are written together
+	tx, err := c.Begin(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
 		return
 	}
+	defer tx.Rollback(r.Context())
 
-	slog.Info("sending back reponse", "message", mssgContent)
-	render.RespondSuccess(w, http.StatusOK, mssgContent)
+	queries := database.New(tx)
+	read, err := queries.MarkMessageRead(r.Context(), database.MarkMessageReadParams{
+		UpdatedAt: time.Now().UTC(),
+		MssgID:    mssgId,
+		ToPvtID:   user.PvtID,
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("could not mark message as read", "mssg_id", mssgId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	// an already read message is not an error, but the message must belong
+	// to the reader
+	mssgContent, err := queries.GetMessageByIdPublic(r.Context(), mssgId)
+	if err != nil || mssgContent.ToUserID != user.UserID {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	}
+	// the sender only hears about it when read receipts are on
+	senderView := convertToPublicMessage(mssgContent, database.User{UserID: mssgContent.FromUserID})
+	if read.MssgID != 0 && senderView.MssgStatus == database.MessageStatusRead {
+		err = queueWebhookEvent(r.Context(), queries, read.FromPvtID, webhookEventMessageRead, pgtype.Int8{Int64: mssgId, Valid: true}, senderView)
+		if err != nil {
+			slog.Error("could not queue read webhook", "mssg_id", mssgId, "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+			return
+		}
+	}
+	err = tx.Commit(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToPublicMessage(mssgContent, user))
 }
 
 func MessageRouter() *chi.Mux {
 	router := chi.NewMux()
 
 	router.Post("/", handleCreateMessage)
+	router.Post("/batch", handleCreateMessageBatch)
+	router.Post("/forward", handleForwardMessages)
+	router.Get("/archived", handleListArchived)
+	router.Get("/mentions", handleListMentions)
+	router.Get("/search", handleSearchMessages)
+	router.Get("/scheduled", handleListScheduled)
+	router.Patch("/scheduled/{schedule_id}", handleReschedule)
+	router.Delete("/scheduled/{schedule_id}", handleCancelScheduled)
+	router.Get("/starred", handleListStarred)
+	router.Get("/unread", handleGetUnread)
+	router.Get("/unread/conversations", handleListUnreadConversations)
+	router.Post("/attachment", handleUploadAttachment)
+	router.Get("/attachment/{attachment_id}", handleGetAttachment)
+	router.Delete("/attachment/{attachment_id}", handleDeleteAttachment)
+	router.Mount("/conversation/{user_id}", ConversationRouter())
+	router.Get("/{mssg_id}", handleGetMessage)
+	router.Post("/{mssg_id}/read", handleReadMessage)
+	router.Get("/{mssg_id}/thread", handleGetThread)
+	router.Post("/{mssg_id}/star", handleStarMessage)
+	router.Delete("/{mssg_id}/star", handleUnstarMessage)
+	router.Post("/{mssg_id}/pin", handlePinMessage)
+	router.Delete("/{mssg_id}/pin", handleUnpinMessage)
+	router.Patch("/{mssg_id}/location", handleUpdateLiveLocation)
+	router.Delete("/{mssg_id}/location/live", handleStopLiveLocation)
+	router.Post("/{mssg_id}/poll/votes/{option_id}", handleVotePoll)
+	router.Delete("/{mssg_id}/poll/votes/{option_id}", handleUnvotePoll)
 
 	return router
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	defaultPageLimit int32 = 20
+	maxPageLimit     int32 = 100
+)
+
+type pageParams struct {
+	Limit  int32 `json:"limit"  validate:"min=1,max=100"`
+	Offset int32 `json:"offset" validate:"min=0"`
+}
+
+type pagedResponse[T any] struct {
+	Data   []T   `json:"data"`
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func parseInt32Query(r *http.Request, key string, fallback int32) (int32, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseInt(val, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return int32(n), nil
+}
+
+func getPageParams(r *http.Request) (pageParams, error) {
+	limit, err := parseInt32Query(r, "limit", defaultPageLimit)
+	if err != nil {
+		return pageParams{}, err
+	}
+	offset, err := parseInt32Query(r, "offset", 0)
+	if err != nil {
+		return pageParams{}, err
+	}
+	return pageParams{
+		Limit:  min(limit, maxPageLimit),
+		Offset: offset,
+	}, nil
+}
+
+func newPagedResponse[T any](data []T, page pageParams) pagedResponse[T] {
+	if data == nil {
+		data = []T{}
+	}
+	return pagedResponse[T]{
+		Data:   data,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// keysetCursor points at the last row of a page sorted newest first, the
+// next page starts right after it
+type keysetCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+func (c keysetCursor) String() string {
+	raw := fmt.Sprintf("%d.%d", c.CreatedAt.UnixMicro(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseKeysetCursor(val string) (keysetCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(val)
+	if err != nil {
+		return keysetCursor{}, errInvalidCursor
+	}
+	var micros, id int64
+	_, err = fmt.Sscanf(string(raw), "%d.%d", &micros, &id)
+	if err != nil {
+		return keysetCursor{}, errInvalidCursor
+	}
+	return keysetCursor{CreatedAt: time.UnixMicro(micros).UTC(), ID: id}, nil
+}
+
+type keysetParams struct {
+	Limit  int32 `json:"limit" validate:"min=1,max=100"`
+	Cursor *keysetCursor
+}
+
+// CursorCreatedAt and CursorID are the query arguments of the cursor, both
+// are NULL on the first page
+func (p keysetParams) CursorCreatedAt() pgtype.Timestamp {
+	if p.Cursor == nil {
+		return pgtype.Timestamp{}
+	}
+	return pgtype.Timestamp{Time: p.Cursor.CreatedAt, Valid: true}
+}
+
+func (p keysetParams) CursorID() pgtype.Int8 {
+	if p.Cursor == nil {
+		return pgtype.Int8{}
+	}
+	return pgtype.Int8{Int64: p.Cursor.ID, Valid: true}
+}
+
+func getKeysetParams(r *http.Request) (keysetParams, error) {
+	limit, err := parseInt32Query(r, "limit", defaultPageLimit)
+	if err != nil {
+		return keysetParams{}, err
+	}
+	page := keysetParams{Limit: min(limit, maxPageLimit)}
+	if val := r.URL.Query().Get("cursor"); val != "" {
+		cursor, err := parseKeysetCursor(val)
+		if err != nil {
+			return keysetParams{}, err
+		}
+		page.Cursor = &cursor
+	}
+	return page, nil
+}
+
+type keysetResponse[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// newKeysetResponse only hands out a cursor when the page is full, a short
+// page is the last one
+func newKeysetResponse[T any](data []T, page keysetParams, cursorOf func(T) keysetCursor) keysetResponse[T] {
+	if data == nil {
+		data = []T{}
+	}
+	resp := keysetResponse[T]{Data: data}
+	if len(data) > 0 && len(data) == int(page.Limit) {
+		resp.NextCursor = cursorOf(data[len(data)-1]).String()
+	}
+	return resp
+}
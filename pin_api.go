@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+)
+
+// maxConversationPins keeps the pinned bar of a conversation short
+const maxConversationPins = 10
+
+// pinMessage counts the pins of the conversation and adds the new one while
+// holding a lock on the conversation, so two pins at the same time cannot
+// both take the last place
+func pinMessage(ctx context.Context, apiCfg apiconf.ApiConfig, user database.User, mssgId int64) (int64, error) {
+	c, err := apiCfg.ConnPool.Acquire(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Release()
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	txQueries := database.New(tx)
+
+	err = txQueries.LockConversationPins(ctx, mssgId)
+	if err != nil {
+		return 0, err
+	}
+	pinned, err := txQueries.PinMessage(ctx, database.PinMessageParams{
+		PinnedByPvtID: user.PvtID,
+		CreatedAt:     time.Now().UTC(),
+		MssgID:        mssgId,
+		MaxPins:       maxConversationPins,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return pinned, tx.Commit(ctx)
+}
+
+// handlePinMessage pins a message for both participants, pinning an already
+// pinned message changes nothing
+func handlePinMessage(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	m, err := getParticipantMessage(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	}
+	if m.Pinned {
+		respondMessageChange(w, r, queries, user, m.MssgID, false)
+		return
+	}
+	slog.Info("pinning message", "user_id", user.UserID, "mssg_id", m.MssgID)
+
+	pinned, err := pinMessage(r.Context(), apiCfg, user, m.MssgID)
+	if err != nil {
+		slog.Error("could not pin message", "mssg_id", m.MssgID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	if pinned == 0 {
+		render.RespondFailure(w, http.StatusConflict, fmt.Sprintf("a conversation can have at most %d pinned messages", maxConversationPins))
+		return
+	}
+	respondMessageChange(w, r, queries, user, m.MssgID, true)
+}
+
+func handleUnpinMessage(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	m, err := getParticipantMessage(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	}
+	slog.Info("unpinning message", "user_id", user.UserID, "mssg_id", m.MssgID)
+
+	unpinned, err := queries.UnpinMessage(r.Context(), m.MssgID)
+	if err != nil {
+		slog.Error("could not unpin message", "mssg_id", m.MssgID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	respondMessageChange(w, r, queries, user, m.MssgID, unpinned != 0)
+}
+
+// handleListPinned lists the pins of a conversation, latest pin first
+func handleListPinned(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	slog.Info("listing pinned messages", "user_id", user.UserID, "counterpart_id", counterpart.UserID)
+
+	rows, err := queries.ListPinnedMessages(r.Context(), database.ListPinnedMessagesParams{
+		UserPvtID:  user.PvtID,
+		OtherPvtID: counterpart.PvtID,
+	})
+	if err != nil {
+		slog.Error("could not list pinned messages", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	messages := make([]PublicMessage, 0, len(rows))
+	for _, m := range rows {
+		messages = append(messages, convertToPublicMessage(database.MessagePublic(m), user))
+	}
+	err = setStarred(r, queries, messages, user)
+	if err != nil {
+		slog.Error("could not list stars", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, messages)
+}
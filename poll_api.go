@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/internal/richtext"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	pollClosedError         = "poll is closed"
+	pollOptionNotFoundError = "could not find poll option"
+)
+
+// createPollData is the poll part of a new poll message, the question is
+// the message body
+type createPollData struct {
+	Options        []string   `json:"options"         validate:"required,min=2,max=12,unique,dive,required,maxgraphemes=100"`
+	MultipleChoice bool       `json:"multiple_choice"`
+	Anonymous      bool       `json:"anonymous"`
+	ClosesAt       *time.Time `json:"closes_at"       validate:"omitnil,gt"`
+}
+
+// cleanPollOptions prepares the options the same way as message bodies,
+// so blank or duplicate options are caught by validation
+func cleanPollOptions(p *createPollData) {
+	if p == nil {
+		return
+	}
+	for n, option := range p.Options {
+		p.Options[n] = strings.TrimSpace(richtext.Clean(option))
+	}
+}
+
+// newPoll holds the poll of a message about to be written
+type newPoll struct {
+	Options        []string
+	MultipleChoice bool
+	Anonymous      bool
+	ClosesAt       pgtype.Timestamp
+}
+
+func convertToNewPoll(p *createPollData) *newPoll {
+	if p == nil {
+		return nil
+	}
+	closesAt := pgtype.Timestamp{}
+	if p.ClosesAt != nil {
+		closesAt = pgtype.Timestamp{Time: p.ClosesAt.UTC(), Valid: true}
+	}
+	return &newPoll{
+		Options:        p.Options,
+		MultipleChoice: p.MultipleChoice,
+		Anonymous:      p.Anonymous,
+		ClosesAt:       closesAt,
+	}
+}
+
+func insertPoll(ctx context.Context, queries *database.Queries, mssgId int64, p newPoll) error {
+	err := queries.CreatePoll(ctx, database.CreatePollParams{
+		MssgID:         mssgId,
+		MultipleChoice: p.MultipleChoice,
+		Anonymous:      p.Anonymous,
+		ClosesAt:       p.ClosesAt,
+	})
+	if err != nil {
+		return err
+	}
+	options := make([]database.CopyPollOptionsParams, 0, len(p.Options))
+	for n, option := range p.Options {
+		options = append(options, database.CopyPollOptionsParams{
+			MssgID:     mssgId,
+			OptionID:   int32(n + 1),
+			OptionText: option,
+		})
+	}
+	_, err = queries.CopyPollOptions(ctx, options)
+	return err
+}
+
+type PublicPollOption struct {
+	OptionID int32         `json:"option_id"`
+	Text     string        `json:"text"`
+	Votes    int32         `json:"votes"`
+	Voters   []pgtype.UUID `json:"voters,omitempty"`
+}
+
+// PublicPoll carries the results of a poll. Voters are only listed when the
+// poll is not anonymous, MyVotes is set by callers that know the viewer.
+type PublicPoll struct {
+	MultipleChoice bool               `json:"multiple_choice"`
+	Anonymous      bool               `json:"anonymous"`
+	ClosesAt       *time.Time         `json:"closes_at,omitempty"`
+	Closed         bool               `json:"closed"`
+	TotalVoters    int32              `json:"total_voters"`
+	Options        []PublicPollOption `json:"options"`
+	MyVotes        []int32            `json:"my_votes,omitempty"`
+}
+
+// convertToPublicPoll decodes the poll column of a message, nil for
+// messages that are not polls
+func convertToPublicPoll(m database.MessagePublic) *PublicPoll {
+	if len(m.Poll) == 0 {
+		return nil
+	}
+	poll := PublicPoll{}
+	err := json.Unmarshal(m.Poll, &poll)
+	if err != nil {
+		slog.Warn("could not decode message poll", "mssg_id", m.MssgID, "error", err)
+		return nil
+	}
+	poll.Closed = poll.ClosesAt != nil && !poll.ClosesAt.After(time.Now().UTC())
+	return &poll
+}
+
+// setMyVotes fills in the options the viewer voted for
+func setMyVotes(ctx context.Context, queries *database.Queries, public *PublicMessage, user database.User) error {
+	if public.Poll == nil {
+		return nil
+	}
+	votes, err := queries.ListPollVotesOfUser(ctx, database.ListPollVotesOfUserParams{
+		MssgID:     public.MssgID,
+		VoterPvtID: user.PvtID,
+	})
+	public.Poll.MyVotes = votes
+	return err
+}
+
+func getOptionIdParam(r *http.Request) (int32, error) {
+	optionId, err := strconv.ParseInt(chi.URLParam(r, "option_id"), 10, 32)
+	return int32(optionId), err
+}
+
+var (
+	errNotAPoll          = errors.New("message is not a poll")
+	errPollClosed        = errors.New(pollClosedError)
+	errPollOptionMissing = errors.New(pollOptionNotFoundError)
+)
+
+// changeVote runs a vote or unvote on a locked poll so a single choice poll
+// never ends up with two votes of one participant, it reports if anything
+// changed
+func changeVote(ctx context.Context, apiCfg apiconf.ApiConfig, m database.MessagePublic, optionId int32, change func(*database.Queries, database.GetPollForUpdateRow) (int64, error)) (bool, error) {
+	if m.MssgType != database.MessageTypePoll {
+		return false, errNotAPoll
+	}
+	c, err := apiCfg.ConnPool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer c.Release()
+	tx, err := c.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := database.New(tx)
+	poll, err := queries.GetPollForUpdate(ctx, m.MssgID)
+	if err != nil {
+		return false, err
+	}
+	if poll.ClosesAt.Valid && !poll.ClosesAt.Time.After(time.Now().UTC()) {
+		return false, errPollClosed
+	}
+	if optionId < 1 || optionId > poll.OptionCount {
+		return false, errPollOptionMissing
+	}
+	changed, err := change(queries, poll)
+	if err != nil {
+		return false, err
+	}
+	return changed != 0, tx.Commit(ctx)
+}
+
+// respondVoteChange turns the errors of a vote or unvote into responses,
+// otherwise the poll goes back with its new results
+func respondVoteChange(w http.ResponseWriter, r *http.Request, m database.MessagePublic, user database.User, changed bool, err error) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	case errors.Is(err, errNotAPoll), errors.Is(err, errPollOptionMissing):
+		render.RespondFailure(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, errPollClosed):
+		render.RespondFailure(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		slog.Error("could not change poll vote", "mssg_id", m.MssgID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	queries := database.New(apiconf.GetConfig(r).ConnPool)
+	respondMessageChange(w, r, queries, user, m.MssgID, changed)
+}
+
+// handleVotePoll adds the caller's vote for an option, in a single choice
+// poll it moves their vote from any other option. Voting twice for the same
+// option changes nothing.
+func handleVotePoll(w http.ResponseWriter, r *http.Request) {
+	optionId, err := getOptionIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid option id")
+		return
+	}
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	m, err := getParticipantMessage(r, queries, user)
+	if err != nil || convertToPublicMessage(m, user).Expired {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	}
+	slog.Info("voting in poll", "user_id", user.UserID, "mssg_id", m.MssgID, "option_id", optionId)
+
+	changed, err := changeVote(r.Context(), apiCfg, m, optionId, func(queries *database.Queries, poll database.GetPollForUpdateRow) (int64, error) {
+		added, err := queries.VotePoll(r.Context(), database.VotePollParams{
+			MssgID:     m.MssgID,
+			OptionID:   optionId,
+			VoterPvtID: user.PvtID,
+			VotedAt:    time.Now().UTC(),
+		})
+		if err != nil || poll.MultipleChoice {
+			return added, err
+		}
+		removed, err := queries.ClearOtherPollVotes(r.Context(), database.ClearOtherPollVotesParams{
+			MssgID:     m.MssgID,
+			VoterPvtID: user.PvtID,
+			OptionID:   optionId,
+		})
+		return added + removed, err
+	})
+	respondVoteChange(w, r, m, user, changed, err)
+}
+
+// handleUnvotePoll takes back the caller's vote for an option
+func handleUnvotePoll(w http.ResponseWriter, r *http.Request) {
+	optionId, err := getOptionIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid option id")
+		return
+	}
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	m, err := getParticipantMessage(r, queries, user)
+	if err != nil || convertToPublicMessage(m, user).Expired {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	}
+	slog.Info("taking back poll vote", "user_id", user.UserID, "mssg_id", m.MssgID, "option_id", optionId)
+
+	changed, err := changeVote(r.Context(), apiCfg, m, optionId, func(queries *database.Queries, _ database.GetPollForUpdateRow) (int64, error) {
+		return queries.UnvotePoll(r.Context(), database.UnvotePollParams{
+			MssgID:     m.MssgID,
+			OptionID:   optionId,
+			VoterPvtID: user.PvtID,
+		})
+	})
+	respondVoteChange(w, r, m, user, changed, err)
+}
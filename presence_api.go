@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/internal/presence"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// presenceHidden is reported for users who hide their last seen time from
+// the caller
+const presenceHidden presence.Status = "hidden"
+
+type PublicPresence struct {
+	UserID       pgtype.UUID     `json:"user_id"`
+	Status       presence.Status `json:"status"`
+	LastActiveAt *time.Time      `json:"last_active_at,omitempty"`
+}
+
+// lookupPresence returns the presence of the visible users among the given
+// ones, users who blocked the caller are left out
+func lookupPresence(r *http.Request, caller database.User, userIds []pgtype.UUID) ([]PublicPresence, error) {
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	targets, err := queries.ListPresenceTargets(r.Context(), database.ListPresenceTargetsParams{
+		CallerPvtID: caller.PvtID,
+		UserIds:     userIds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pvtIds := make([]int32, 0, len(targets))
+	for _, t := range targets {
+		if t.LastSeenVisible {
+			pvtIds = append(pvtIds, t.PvtID)
+		}
+	}
+	states, err := apiCfg.Presence.Lookup(r.Context(), pvtIds)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]PublicPresence, 0, len(targets))
+	for _, t := range targets {
+		if !t.LastSeenVisible {
+			result = append(result, PublicPresence{UserID: t.UserID, Status: presenceHidden})
+			continue
+		}
+		state := states[t.PvtID]
+		lastActive := state.LastActiveAt
+		// users who were never seen by the registry fall back to their last login
+		if lastActive.IsZero() && t.LastLoggedIn.Valid {
+			lastActive = t.LastLoggedIn.Time
+		}
+		p := PublicPresence{
+			UserID: t.UserID,
+			Status: If(state.Status == "", presence.StatusOffline, state.Status),
+		}
+		if !lastActive.IsZero() {
+			p.LastActiveAt = &lastActive
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+func handleGetPresence(w http.ResponseWriter, r *http.Request) {
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	caller := auth.GetUserData(r)
+	slog.Info("getting presence", "user_id", userId, "caller_id", caller.UserID)
+
+	result, err := lookupPresence(r, caller, []pgtype.UUID{userId})
+	if err != nil {
+		slog.Error("could not lookup presence", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	if len(result) == 0 {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, result[0])
+}
+
+type batchPresenceData struct {
+	UserIds []pgtype.UUID `json:"user_ids" validate:"required,min=1,max=100"`
+}
+
+func handleBatchPresence(w http.ResponseWriter, r *http.Request) {
+	data := batchPresenceData{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&data)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+
+	apiCfg := apiconf.GetConfig(r)
+	// validate incoming data
+	err = apiCfg.Validate.Struct(data)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	caller := auth.GetUserData(r)
+	slog.Info("getting presence in batch", "count", len(data.UserIds), "caller_id", caller.UserID)
+
+	result, err := lookupPresence(r, caller, data.UserIds)
+	if err != nil {
+		slog.Error("could not lookup presence", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, result)
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -13,12 +14,25 @@ type errorMsg struct {
 	Message any `json:"message"`
 }
 
-func RespondValidationFailure(w http.ResponseWriter, validationErrors validator.ValidationErrors) {
+// ValidationMessages maps each failed field to a readable message, in the
+// request's language when a translator is given
+func ValidationMessages(validationErrors validator.ValidationErrors, trans ut.Translator) map[string]string {
 	errorMssgs := make(map[string]string)
 	for _, fieldError := range validationErrors {
-		errorMssgs[fieldError.Field()] = fmt.Sprintf("failed on %s with value '%s'", fieldError.ActualTag(), fieldError.Value())
+		mssg := fmt.Sprintf("failed on %s with value '%s'", fieldError.ActualTag(), fieldError.Value())
+		// untranslated tags fall back to the raw validator error
+		if trans != nil {
+			if translated := fieldError.Translate(trans); translated != fieldError.Error() {
+				mssg = translated
+			}
+		}
+		errorMssgs[fieldError.Field()] = mssg
 	}
-	RespondFailure(w, http.StatusBadRequest, errorMssgs)
+	return errorMssgs
+}
+
+func RespondValidationFailure(w http.ResponseWriter, validationErrors validator.ValidationErrors, trans ut.Translator) {
+	RespondFailure(w, http.StatusBadRequest, ValidationMessages(validationErrors, trans))
 }
 
 func RespondFailure(w http.ResponseWriter, code int, msg any) {
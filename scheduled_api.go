@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	schedulerInterval            = 5 * time.Second
+	schedulerBatchSize     int32 = 50
+	scheduledNotFoundError       = "could not find scheduled message"
+)
+
+type PublicScheduledMessage struct {
+	ScheduleID    int64                    `json:"schedule_id"`
+	ToUserID      pgtype.UUID              `json:"to_user_id"`
+	MssgType      database.MessageType     `json:"mssg_type"`
+	AttachMssgID  pgtype.Int8              `json:"attach_mssg_id"`
+	AttachmentID  pgtype.UUID              `json:"attachment_id"`
+	MssgBody      string                   `json:"mssg_body"`
+	Format        database.MessageFormat   `json:"format"`
+	SendAt        time.Time                `json:"send_at"`
+	Status        database.ScheduledStatus `json:"status"`
+	MssgID        pgtype.Int8              `json:"mssg_id"`
+	FailureReason string                   `json:"failure_reason,omitempty"`
+	TtlSeconds    pgtype.Int4              `json:"ttl_seconds"`
+	CreatedAt     time.Time                `json:"created_at"`
+	UpdatedAt     time.Time                `json:"updated_at"`
+}
+
+func convertToPublicScheduled(sm database.ScheduledMessage, toUserId pgtype.UUID) PublicScheduledMessage {
+	return PublicScheduledMessage{
+		ScheduleID:    sm.ScheduleID,
+		ToUserID:      toUserId,
+		MssgType:      sm.MssgType,
+		AttachMssgID:  sm.AttachMssgID,
+		AttachmentID:  sm.AttachmentID,
+		MssgBody:      sm.MssgBody,
+		Format:        sm.MssgFormat,
+		SendAt:        sm.SendAt,
+		Status:        sm.Status,
+		MssgID:        sm.MssgID,
+		FailureReason: sm.FailureReason.String,
+		TtlSeconds:    sm.TtlSeconds,
+		CreatedAt:     sm.CreatedAt,
+		UpdatedAt:     sm.UpdatedAt,
+	}
+}
+
+func getScheduleIdParam(r *http.Request) (int64, error) {
+	return strconv.ParseInt(chi.URLParam(r, "schedule_id"), 10, 64)
+}
+
+// scheduleMessage stores a message that the scheduler sends at sendAt. The
+// recipient's privacy settings are checked again at that time.
+func scheduleMessage(ctx context.Context, queries *database.Queries, m newMessage, sendAt time.Time) (database.ScheduledMessage, error) {
+	err := checkReplyTarget(ctx, queries, m)
+	if err != nil {
+		return database.ScheduledMessage{}, err
+	}
+	if m.AttachmentID.Valid {
+		attachment, err := queries.GetAttachmentForUser(ctx, database.GetAttachmentForUserParams{
+			AttachmentID: m.AttachmentID,
+			CallerPvtID:  m.FromPvtID,
+			Now:          timestampNow(),
+		})
+		if err != nil || attachment.OwnerPvtID != m.FromPvtID || attachment.MssgID.Valid {
+			return database.ScheduledMessage{}, errAttachmentNotFound
+		}
+	}
+
+	now := time.Now().UTC()
+	return queries.CreateScheduledMessage(ctx, database.CreateScheduledMessageParams{
+		FromPvtID:    m.FromPvtID,
+		ToPvtID:      m.ToPvtID,
+		MssgType:     m.MssgType,
+		AttachMssgID: m.AttachMssgID,
+		AttachmentID: m.AttachmentID,
+		MssgBody:     m.MssgBody,
+		SendAt:       sendAt.UTC(),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		TtlSeconds:   pgtype.Int4{Int32: m.TtlSeconds, Valid: m.TtlSeconds != 0},
+		MssgFormat:   m.Format,
+	})
+}
+
+func handleListScheduled(w http.ResponseWriter, r *http.Request) {
+	page, err := getPageParams(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid pagination parameters")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(page)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("listing scheduled messages", "user_id", user.UserID)
+
+	queries := database.New(apiCfg.ConnPool)
+	rows, err := queries.ListScheduledMessages(r.Context(), database.ListScheduledMessagesParams{
+		FromPvtID: user.PvtID,
+		Limit:     page.Limit,
+		Offset:    page.Offset,
+	})
+	if err != nil {
+		slog.Error("could not list scheduled messages", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	scheduled := make([]PublicScheduledMessage, 0, len(rows))
+	for _, sm := range rows {
+		scheduled = append(scheduled, convertToPublicScheduled(database.ScheduledMessage{
+			ScheduleID:    sm.ScheduleID,
+			FromPvtID:     sm.FromPvtID,
+			ToPvtID:       sm.ToPvtID,
+			MssgType:      sm.MssgType,
+			AttachMssgID:  sm.AttachMssgID,
+			AttachmentID:  sm.AttachmentID,
+			MssgBody:      sm.MssgBody,
+			SendAt:        sm.SendAt,
+			Status:        sm.Status,
+			MssgID:        sm.MssgID,
+			FailureReason: sm.FailureReason,
+			CreatedAt:     sm.CreatedAt,
+			UpdatedAt:     sm.UpdatedAt,
+			TtlSeconds:    sm.TtlSeconds,
+			MssgFormat:    sm.MssgFormat,
+		}, sm.ToUserID))
+	}
+	render.RespondSuccess(w, http.StatusOK, newPagedResponse(scheduled, page))
+}
+
+type rescheduleData struct {
+	SendAt time.Time `json:"send_at" validate:"required,gt"`
+}
+
+// handleReschedule moves a pending message to a new time, a failed one is
+// retried at that time
+func handleReschedule(w http.ResponseWriter, r *http.Request) {
+	scheduleId, err := getScheduleIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid schedule id")
+		return
+	}
+	data := rescheduleData{}
+	decoder := json.NewDecoder(r.Body)
+	err = decoder.Decode(&data)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+
+	apiCfg := apiconf.GetConfig(r)
+	// validate incoming data
+	err = apiCfg.Validate.Struct(data)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("rescheduling message", "user_id", user.UserID, "schedule_id", scheduleId)
+
+	queries := database.New(apiCfg.ConnPool)
+	sm, err := queries.RescheduleMessage(r.Context(), database.RescheduleMessageParams{
+		SendAt:     data.SendAt.UTC(),
+		UpdatedAt:  time.Now().UTC(),
+		ScheduleID: scheduleId,
+		FromPvtID:  user.PvtID,
+	})
+	respondScheduled(w, r, queries, sm, err)
+}
+
+func handleCancelScheduled(w http.ResponseWriter, r *http.Request) {
+	scheduleId, err := getScheduleIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid schedule id")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("cancelling scheduled message", "user_id", user.UserID, "schedule_id", scheduleId)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	sm, err := queries.CancelScheduledMessage(r.Context(), database.CancelScheduledMessageParams{
+		ScheduleID: scheduleId,
+		FromPvtID:  user.PvtID,
+	})
+	respondScheduled(w, r, queries, sm, err)
+}
+
+func respondScheduled(w http.ResponseWriter, r *http.Request, queries *database.Queries, sm database.ScheduledMessage, err error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondFailure(w, http.StatusNotFound, scheduledNotFoundError)
+		return
+	} else if err != nil {
+		slog.Error("could not update scheduled message", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	toUser, err := queries.GetUserById(r.Context(), sm.ToPvtID)
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToPublicScheduled(sm, toUser.UserID))
+}
+
+// runScheduler sends scheduled messages once they are due. Every instance
+// runs one, SKIP LOCKED hands each message to exactly one of them.
+func runScheduler(ctx context.Context, apiCfg apiconf.ApiConfig) {
+	runBatches(ctx, "scheduler", schedulerInterval, schedulerBatchSize, func(ctx context.Context) (int32, error) {
+		return sendDueMessages(ctx, apiCfg)
+	})
+}
+
+func sendDueMessages(ctx context.Context, apiCfg apiconf.ApiConfig) (int32, error) {
+	tx, err := apiCfg.ConnPool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := database.New(apiCfg.ConnPool).WithTx(tx)
+	due, err := queries.ClaimDueScheduledMessages(ctx, database.ClaimDueScheduledMessagesParams{
+		SendAt: time.Now().UTC(),
+		Limit:  schedulerBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+	sent := make([]sentMessage, 0, len(due))
+	for _, sm := range due {
+		mssgContent, err := materializeScheduled(ctx, tx, queries, sm)
+		if err != nil {
+			slog.Warn("could not send scheduled message", "schedule_id", sm.ScheduleID, "error", err)
+			err = queries.MarkScheduledFailed(ctx, database.MarkScheduledFailedParams{
+				FailureReason: pgtype.Text{String: scheduledFailureReason(err), Valid: true},
+				UpdatedAt:     time.Now().UTC(),
+				ScheduleID:    sm.ScheduleID,
+			})
+			if err != nil {
+				return 0, err
+			}
+			continue
+		}
+		err = queries.MarkScheduledSent(ctx, database.MarkScheduledSentParams{
+			MssgID:     pgtype.Int8{Int64: mssgContent.MssgID, Valid: true},
+			UpdatedAt:  time.Now().UTC(),
+			ScheduleID: sm.ScheduleID,
+		})
+		if err != nil {
+			return 0, err
+		}
+		sent = append(sent, sentMessage{mssgContent, sm.FromPvtID, sm.ToPvtID})
+	}
+	err = queueMessageWebhooks(ctx, queries, sent)
+	if err != nil {
+		return 0, err
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range sent {
+		publishMessage(ctx, apiCfg.Hub, m.content, m.fromPvtId, m.toPvtId)
+	}
+	notifyRecipients(ctx, apiCfg, sent)
+	return int32(len(due)), nil
+}
+
+// materializeScheduled checks and writes the message inside a savepoint, so
+// a failing message, even one failing in the database, does not take the
+// rest of the batch down with it
+func materializeScheduled(ctx context.Context, tx pgx.Tx, queries *database.Queries, sm database.ScheduledMessage) (database.MessagePublic, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+	defer savepoint.Rollback(ctx)
+	spQueries := queries.WithTx(savepoint)
+
+	fromUser, err := spQueries.GetUserById(ctx, sm.FromPvtID)
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+	toUser, err := spQueries.GetUserById(ctx, sm.ToPvtID)
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+	err = checkCanMessage(ctx, spQueries, fromUser, toUser)
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+	mssgContent, err := insertMessage(ctx, spQueries, newMessage{
+		FromPvtID:    sm.FromPvtID,
+		ToPvtID:      sm.ToPvtID,
+		MssgType:     sm.MssgType,
+		AttachMssgID: sm.AttachMssgID,
+		AttachmentID: sm.AttachmentID,
+		MssgBody:     sm.MssgBody,
+		Format:       sm.MssgFormat,
+		TtlSeconds:   sm.TtlSeconds.Int32,
+	})
+	if err != nil {
+		return database.MessagePublic{}, err
+	}
+	return mssgContent, savepoint.Commit(ctx)
+}
+
+func scheduledFailureReason(err error) string {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return "recipient is no longer available"
+	case errors.Is(err, errContactsOnly), errors.Is(err, errAttachmentNotFound), errors.Is(err, errReplyTargetNotFound):
+		return err.Error()
+	default:
+		return "could not send message"
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"html"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// SearchMessages marks the matched words with these characters, after taking
+// them out of the body itself. The snippet is escaped before they turn into
+// html tags.
+var snippetReplacer = strings.NewReplacer("\x02", "<mark>", "\x03", "</mark>")
+
+type MessageSearchResult struct {
+	MssgID     int64                `json:"mssg_id"`
+	FromUserID pgtype.UUID          `json:"from_user_id"`
+	ToUserID   pgtype.UUID          `json:"to_user_id"`
+	CreatedAt  time.Time            `json:"created_at"`
+	MssgType   database.MessageType `json:"mssg_type"`
+	Snippet    string               `json:"snippet"`
+	Starred    bool                 `json:"starred"`
+}
+
+func convertToSearchResult(m database.SearchMessagesRow) MessageSearchResult {
+	return MessageSearchResult{
+		MssgID:     m.MssgID,
+		FromUserID: m.FromUserID,
+		ToUserID:   m.ToUserID,
+		CreatedAt:  m.CreatedAt,
+		MssgType:   m.MssgType,
+		Snippet:    snippetReplacer.Replace(html.EscapeString(m.Snippet)),
+	}
+}
+
+type searchMessagesData struct {
+	Query    string      `json:"q"    validate:"required,max=256"`
+	With     pgtype.UUID `json:"with"`
+	MssgType string      `json:"type" validate:"omitempty,oneof=normal reply reaction attachment poll location contact"`
+	After    pgtype.Timestamp
+	Before   pgtype.Timestamp
+	keysetParams
+}
+
+func parseTimeQuery(r *http.Request, key string) (pgtype.Timestamp, error) {
+	val := r.URL.Query().Get(key)
+	if val == "" {
+		return pgtype.Timestamp{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return pgtype.Timestamp{}, err
+	}
+	return pgtype.Timestamp{Time: t.UTC(), Valid: true}, nil
+}
+
+func getSearchMessagesData(r *http.Request) (searchMessagesData, error) {
+	page, err := getKeysetParams(r)
+	if err != nil {
+		return searchMessagesData{}, err
+	}
+	sd := searchMessagesData{
+		Query:        strings.TrimSpace(r.URL.Query().Get("q")),
+		MssgType:     r.URL.Query().Get("type"),
+		keysetParams: page,
+	}
+	if val := r.URL.Query().Get("with"); val != "" {
+		err = sd.With.Scan(val)
+		if err != nil {
+			return searchMessagesData{}, err
+		}
+	}
+	sd.After, err = parseTimeQuery(r, "after")
+	if err != nil {
+		return searchMessagesData{}, err
+	}
+	sd.Before, err = parseTimeQuery(r, "before")
+	if err != nil {
+		return searchMessagesData{}, err
+	}
+	return sd, nil
+}
+
+// handleSearchMessages looks through the messages the caller sent or
+// received, newest first
+func handleSearchMessages(w http.ResponseWriter, r *http.Request) {
+	sd, err := getSearchMessagesData(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid search parameters")
+		return
+	}
+
+	apiCfg := apiconf.GetConfig(r)
+	// validate incoming data
+	err = apiCfg.Validate.Struct(sd)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	caller := auth.GetUserData(r)
+	slog.Info("searching messages", "query", sd.Query, "caller_id", caller.UserID)
+
+	queries := database.New(apiCfg.ConnPool)
+	counterpart := pgtype.Int4{}
+	if sd.With.Valid {
+		user, err := queries.GetUserByUuid(r.Context(), sd.With)
+		if err != nil {
+			render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+			return
+		}
+		counterpart = pgtype.Int4{Int32: user.PvtID, Valid: true}
+	}
+	rows, err := queries.SearchMessages(r.Context(), database.SearchMessagesParams{
+		Query:            sd.Query,
+		CallerPvtID:      caller.PvtID,
+		Now:              timestampNow(),
+		CounterpartPvtID: counterpart,
+		SentAfter:        sd.After,
+		SentBefore:       sd.Before,
+		MssgType: database.NullMessageType{
+			MessageType: database.MessageType(sd.MssgType),
+			Valid:       sd.MssgType != "",
+		},
+		CursorCreatedAt: sd.CursorCreatedAt(),
+		CursorMssgID:    sd.CursorID(),
+		PageLimit:       sd.Limit,
+	})
+	if err != nil {
+		slog.Error("could not search messages", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	mssgIds := make([]int64, 0, len(rows))
+	for _, m := range rows {
+		mssgIds = append(mssgIds, m.MssgID)
+	}
+	starred, err := listStarred(r, queries, caller, mssgIds)
+	if err != nil {
+		slog.Error("could not list stars", "caller_id", caller.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	results := make([]MessageSearchResult, 0, len(rows))
+	for _, m := range rows {
+		result := convertToSearchResult(m)
+		result.Starred = starred[m.MssgID]
+		results = append(results, result)
+	}
+	render.RespondSuccess(w, http.StatusOK, newKeysetResponse(results, sd.keysetParams, func(m MessageSearchResult) keysetCursor {
+		return keysetCursor{CreatedAt: m.CreatedAt, ID: m.MssgID}
+	}))
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+)
+
+type PublicUserSettings struct {
+	AcceptMessagesFrom database.MessagePrivacy `json:"accept_messages_from"`
+	LastSeenVisibility database.Visibility     `json:"last_seen_visibility"`
+	SendReadReceipts   bool                    `json:"send_read_receipts"`
+	UpdatedAt          time.Time               `json:"updated_at"`
+}
+
+func convertToPublicSettings(s database.UserSetting) PublicUserSettings {
+	return PublicUserSettings{
+		AcceptMessagesFrom: s.AcceptMessagesFrom,
+		LastSeenVisibility: s.LastSeenVisibility,
+		SendReadReceipts:   s.SendReadReceipts,
+		UpdatedAt:          s.UpdatedAt,
+	}
+}
+
+// getUserSettings falls back to the defaults for users who never changed
+// their settings
+func getUserSettings(ctx context.Context, queries *database.Queries, pvtId int32) (database.UserSetting, error) {
+	settings, err := queries.GetUserSettings(ctx, pvtId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return database.UserSetting{
+			PvtID:              pvtId,
+			AcceptMessagesFrom: database.MessagePrivacyEveryone,
+			LastSeenVisibility: database.VisibilityEveryone,
+			SendReadReceipts:   true,
+		}, nil
+	}
+	return settings, err
+}
+
+// isVisibleTo decides if something guarded by a visibility setting of the
+// settings owner can be shown to the viewer
+func isVisibleTo(ctx context.Context, queries *database.Queries, visibility database.Visibility, ownerPvtId, viewerPvtId int32) (bool, error) {
+	if ownerPvtId == viewerPvtId {
+		return true, nil
+	}
+	switch visibility {
+	case database.VisibilityEveryone:
+		return true, nil
+	case database.VisibilityContacts:
+		return queries.IsContact(ctx, database.IsContactParams{
+			OwnerPvtID:   ownerPvtId,
+			ContactPvtID: viewerPvtId,
+		})
+	default:
+		return false, nil
+	}
+}
+
+func handleGetUserSettings(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	slog.Info("getting user settings", "user_id", user.UserID, "user_name", user.Username)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	settings, err := getUserSettings(r.Context(), queries, user.PvtID)
+	if err != nil {
+		slog.Error("could not fetch user settings", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToPublicSettings(settings))
+}
+
+type updateSettingsData struct {
+	AcceptMessagesFrom *string `json:"accept_messages_from" validate:"omitnil,oneof=everyone contacts"`
+	LastSeenVisibility *string `json:"last_seen_visibility" validate:"omitnil,oneof=everyone contacts nobody"`
+	SendReadReceipts   *bool   `json:"send_read_receipts"`
+}
+
+func handleUpdateUserSettings(w http.ResponseWriter, r *http.Request) {
+	sd := updateSettingsData{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&sd)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("updating user settings", "user_id", user.UserID, "user_name", user.Username)
+
+	apiCfg := apiconf.GetConfig(r)
+	// validate incoming data
+	err = apiCfg.Validate.Struct(sd)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	queries := database.New(apiCfg.ConnPool)
+	settings, err := getUserSettings(r.Context(), queries, user.PvtID)
+	if err != nil {
+		slog.Error("could not fetch user settings", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	// find the updated fields
+	if sd.AcceptMessagesFrom != nil {
+		settings.AcceptMessagesFrom = database.MessagePrivacy(*sd.AcceptMessagesFrom)
+	}
+	if sd.LastSeenVisibility != nil {
+		settings.LastSeenVisibility = database.Visibility(*sd.LastSeenVisibility)
+	}
+	if sd.SendReadReceipts != nil {
+		settings.SendReadReceipts = *sd.SendReadReceipts
+	}
+	updSettings, err := queries.UpsertUserSettings(r.Context(), database.UpsertUserSettingsParams{
+		PvtID:              user.PvtID,
+		AcceptMessagesFrom: settings.AcceptMessagesFrom,
+		LastSeenVisibility: settings.LastSeenVisibility,
+		SendReadReceipts:   settings.SendReadReceipts,
+		UpdatedAt:          time.Now().UTC(),
+	})
+	if err != nil {
+		slog.Error("could not update user settings", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInsufficientStorage, "could not update at this time")
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToPublicSettings(updSettings))
+}
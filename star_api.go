@@ -0,0 +1,148 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+)
+
+// listStarred looks up which of the messages the user starred, in one query
+// for a whole page
+func listStarred(r *http.Request, queries *database.Queries, user database.User, mssgIds []int64) (map[int64]bool, error) {
+	starred := make(map[int64]bool)
+	if len(mssgIds) == 0 {
+		return starred, nil
+	}
+	ids, err := queries.ListStarredMessageIds(r.Context(), database.ListStarredMessageIdsParams{
+		OwnerPvtID: user.PvtID,
+		MssgIds:    mssgIds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		starred[id] = true
+	}
+	return starred, nil
+}
+
+// setStarred fills in whether the viewer starred each of the messages
+func setStarred(r *http.Request, queries *database.Queries, messages []PublicMessage, user database.User) error {
+	mssgIds := make([]int64, 0, len(messages))
+	for _, m := range messages {
+		mssgIds = append(mssgIds, m.MssgID)
+	}
+	starred, err := listStarred(r, queries, user, mssgIds)
+	if err != nil {
+		return err
+	}
+	for n := range messages {
+		isStarred := starred[messages[n].MssgID]
+		messages[n].Starred = &isStarred
+	}
+	return nil
+}
+
+// handleStarMessage bookmarks a message for the caller only, the other
+// participant never learns about it
+func handleStarMessage(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	m, err := getParticipantMessage(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	}
+	slog.Info("starring message", "user_id", user.UserID, "mssg_id", m.MssgID)
+
+	err = queries.StarMessage(r.Context(), database.StarMessageParams{
+		OwnerPvtID: user.PvtID,
+		MssgID:     m.MssgID,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		slog.Error("could not star message", "mssg_id", m.MssgID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	starred := true
+	public := convertToPublicMessage(m, user)
+	public.Starred = &starred
+	render.RespondSuccess(w, http.StatusOK, public)
+}
+
+func handleUnstarMessage(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	m, err := getParticipantMessage(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	}
+	slog.Info("unstarring message", "user_id", user.UserID, "mssg_id", m.MssgID)
+
+	_, err = queries.UnstarMessage(r.Context(), database.UnstarMessageParams{
+		OwnerPvtID: user.PvtID,
+		MssgID:     m.MssgID,
+	})
+	if err != nil {
+		slog.Error("could not unstar message", "mssg_id", m.MssgID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	starred := false
+	public := convertToPublicMessage(m, user)
+	public.Starred = &starred
+	render.RespondSuccess(w, http.StatusOK, public)
+}
+
+// handleListStarred lists the caller's starred messages, latest star first
+func handleListStarred(w http.ResponseWriter, r *http.Request) {
+	page, err := getPageParams(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid pagination parameters")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(page)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("listing starred messages", "user_id", user.UserID)
+
+	queries := database.New(apiCfg.ConnPool)
+	rows, err := queries.ListStarredMessages(r.Context(), database.ListStarredMessagesParams{
+		OwnerPvtID: user.PvtID,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+	})
+	if err != nil {
+		slog.Error("could not list starred messages", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	starred := true
+	messages := make([]PublicMessage, 0, len(rows))
+	for _, m := range rows {
+		public := convertToPublicMessage(database.MessagePublic(m), user)
+		public.Starred = &starred
+		messages = append(messages, public)
+	}
+	render.RespondSuccess(w, http.StatusOK, newPagedResponse(messages, page))
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+)
+
+// PublicThreadSummary tells how many replies, direct or nested, a message
+// has and when the last one was sent
+type PublicThreadSummary struct {
+	ReplyCount  int32      `json:"reply_count"`
+	LastReplyAt *time.Time `json:"last_reply_at,omitempty"`
+}
+
+type ThreadView struct {
+	Root    PublicMessage                `json:"root"`
+	Replies pagedResponse[PublicMessage] `json:"replies"`
+}
+
+// conversationPair orders the participants of a conversation the way
+// threads are looked up
+func conversationPair(a, b int32) (int32, int32) {
+	return min(a, b), max(a, b)
+}
+
+// setThreadSummaries fills in the thread of the messages that have replies
+func setThreadSummaries(r *http.Request, queries *database.Queries, messages []PublicMessage, low, high int32) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	mssgIds := make([]int64, 0, len(messages))
+	for _, m := range messages {
+		mssgIds = append(mssgIds, m.MssgID)
+	}
+	summaries, err := queries.ListThreadSummaries(r.Context(), database.ListThreadSummariesParams{
+		RootIds:   mssgIds,
+		LowPvtID:  low,
+		HighPvtID: high,
+	})
+	if err != nil {
+		return err
+	}
+	byRoot := make(map[int64]database.ListThreadSummariesRow, len(summaries))
+	for _, s := range summaries {
+		byRoot[s.RootID] = s
+	}
+	for n := range messages {
+		s, ok := byRoot[messages[n].MssgID]
+		if !ok {
+			continue
+		}
+		messages[n].Thread = &PublicThreadSummary{ReplyCount: s.ReplyCount, LastReplyAt: &s.LastReplyAt}
+	}
+	return nil
+}
+
+// handleGetThread shows the thread a message belongs to, the root first and
+// then every reply below it oldest first. Reactions are not part of it.
+func handleGetThread(w http.ResponseWriter, r *http.Request) {
+	page, err := getPageParams(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid pagination parameters")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(page)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	queries := database.New(apiCfg.ConnPool)
+	m, err := getParticipantMessage(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	}
+	slog.Info("fetching thread", "user_id", user.UserID, "mssg_id", m.MssgID)
+
+	participants, err := queries.GetMessageById(r.Context(), m.MssgID)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+		return
+	}
+	low, high := conversationPair(participants.FromPvtID, participants.ToPvtID)
+	// replies only ever point inside their own conversation
+	rootId, err := queries.GetThreadRootId(r.Context(), database.GetThreadRootIdParams{
+		MssgID:    m.MssgID,
+		LowPvtID:  low,
+		HighPvtID: high,
+	})
+	if err != nil {
+		slog.Error("could not find thread root", "mssg_id", m.MssgID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	root := m
+	if rootId != m.MssgID {
+		root, err = queries.GetMessageByIdPublic(r.Context(), rootId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			render.RespondFailure(w, http.StatusNotFound, messageNotFoundError)
+			return
+		} else if err != nil {
+			slog.Error("could not fetch thread root", "mssg_id", rootId, "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+			return
+		}
+	}
+	rows, err := queries.ListThreadReplies(r.Context(), database.ListThreadRepliesParams{
+		RootID:     rootId,
+		LowPvtID:   low,
+		HighPvtID:  high,
+		PageLimit:  page.Limit,
+		PageOffset: page.Offset,
+	})
+	if err != nil {
+		slog.Error("could not list thread replies", "mssg_id", rootId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	replies := make([]PublicMessage, 0, len(rows))
+	for _, reply := range rows {
+		replies = append(replies, convertToPublicMessage(database.MessagePublic(reply), user))
+	}
+	// a root without replies still gets a summary, with nothing in it
+	publicRoot := []PublicMessage{convertToPublicMessage(root, user)}
+	err = setThreadSummaries(r, queries, publicRoot, low, high)
+	if err != nil {
+		slog.Error("could not summarize thread", "mssg_id", rootId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	if publicRoot[0].Thread == nil {
+		publicRoot[0].Thread = &PublicThreadSummary{}
+	}
+	// the stars of the root and the replies are looked up together
+	thread := append(publicRoot, replies...)
+	err = setStarred(r, queries, thread, user)
+	if err != nil {
+		slog.Error("could not list stars", "mssg_id", rootId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	publicRoot, replies = thread[:1], thread[1:]
+	render.RespondSuccess(w, http.StatusOK, ThreadView{
+		Root:    publicRoot[0],
+		Replies: newPagedResponse(replies, page),
+	})
+}
+
+// handleListConversationMessages is the timeline of a conversation, latest
+// message first. With exclude_thread_replies the replies only show up in
+// their threads and the messages they answer carry the thread summary.
+func handleListConversationMessages(w http.ResponseWriter, r *http.Request) {
+	page, err := getKeysetParams(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid pagination parameters")
+		return
+	}
+	excludeReplies := false
+	if val := r.URL.Query().Get("exclude_thread_replies"); val != "" {
+		excludeReplies, err = strconv.ParseBool(val)
+		if err != nil {
+			render.RespondFailure(w, http.StatusBadRequest, "invalid exclude_thread_replies parameter")
+			return
+		}
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(page)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	queries := database.New(apiCfg.ConnPool)
+	counterpart, err := getCounterpart(r, queries, user)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	slog.Info("listing conversation messages", "user_id", user.UserID, "counterpart_id", counterpart.UserID)
+
+	rows, err := queries.ListConversationMessages(r.Context(), database.ListConversationMessagesParams{
+		UserPvtID:            user.PvtID,
+		OtherPvtID:           counterpart.PvtID,
+		ExcludeThreadReplies: excludeReplies,
+		CursorCreatedAt:      page.CursorCreatedAt(),
+		CursorMssgID:         page.CursorID(),
+		PageLimit:            page.Limit,
+	})
+	if err != nil {
+		slog.Error("could not list conversation messages", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	messages := make([]PublicMessage, 0, len(rows))
+	for _, m := range rows {
+		messages = append(messages, convertToPublicMessage(database.MessagePublic(m), user))
+	}
+	low, high := conversationPair(user.PvtID, counterpart.PvtID)
+	err = setThreadSummaries(r, queries, messages, low, high)
+	if err != nil {
+		slog.Error("could not summarize threads", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	err = setStarred(r, queries, messages, user)
+	if err != nil {
+		slog.Error("could not list stars", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, newKeysetResponse(messages, page, func(m PublicMessage) keysetCursor {
+		return keysetCursor{CreatedAt: m.CreatedAt, ID: m.MssgID}
+	}))
+}
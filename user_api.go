@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Suryarpan/chat-api/internal/apiconf"
@@ -14,32 +16,68 @@ import (
 	"github.com/Suryarpan/chat-api/render"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const (
 	insufficientStorageUserError = "could not create user at this moment"
+	userNotFoundError            = "could not find user"
 )
 
 type PublicUserDetails struct {
-	UserID       pgtype.UUID      `json:"user_id"`
-	Username     string           `json:"username"`
-	DisplayName  string           `json:"display_name"`
-	CreatedAt    time.Time        `json:"created_at"`
-	UpdatedAt    time.Time        `json:"updated_at,omitempty"`
-	LastLoggedIn pgtype.Timestamp `json:"last_logged_in,omitempty"`
+	UserID       pgtype.UUID       `json:"user_id"`
+	Username     string            `json:"username"`
+	DisplayName  string            `json:"display_name"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at,omitempty"`
+	LastLoggedIn *pgtype.Timestamp `json:"last_logged_in,omitempty"`
+	AvatarURL    string            `json:"avatar_url,omitempty"`
+	Bio          string            `json:"bio"`
+	StatusText   string            `json:"status_text,omitempty"`
+	StatusExpiry *pgtype.Timestamp `json:"status_expires_at,omitempty"`
+	Timezone     string            `json:"timezone,omitempty"`
+	Locale       string            `json:"locale,omitempty"`
+}
+
+// activeStatus hides custom status lines that have expired
+func activeStatus(u database.User) (string, *pgtype.Timestamp) {
+	if u.StatusText == "" {
+		return "", nil
+	}
+	if !u.StatusExpiresAt.Valid {
+		return u.StatusText, nil
+	}
+	if u.StatusExpiresAt.Time.Before(time.Now().UTC()) {
+		return "", nil
+	}
+	return u.StatusText, &u.StatusExpiresAt
 }
 
 func convertToPublicUser(u database.User) PublicUserDetails {
 	slog.Debug("converting to public data", "user_id", u.UserID, "user_name", u.Username)
+	publicUser := convertToReducedUser(u)
+	publicUser.LastLoggedIn = &u.LastLoggedIn
+	publicUser.Timezone = u.Timezone
+	publicUser.Locale = u.Locale
+	return publicUser
+}
+
+// convertToReducedUser is the view of a user shown to everyone other than
+// the user themselves
+func convertToReducedUser(u database.User) PublicUserDetails {
+	statusText, statusExpiry := activeStatus(u)
 	return PublicUserDetails{
 		UserID:       u.UserID,
 		Username:     u.Username,
 		DisplayName:  u.DisplayName,
 		CreatedAt:    u.CreatedAt,
 		UpdatedAt:    u.UpdatedAt,
-		LastLoggedIn: u.LastLoggedIn,
+		AvatarURL:    avatarURL(u),
+		Bio:          u.Bio,
+		StatusText:   statusText,
+		StatusExpiry: statusExpiry,
 	}
 }
 
@@ -76,7 +114,7 @@ func handleCreateUser(w http.ResponseWriter, r *http.Request) {
 			slog.Error("error with validator definition", "error", err)
 			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
 		} else {
-			render.RespondValidationFailure(w, validationErrors)
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
 		}
 		return
 	}
@@ -129,9 +167,14 @@ func handleCreateUser(w http.ResponseWriter, r *http.Request) {
 }
 
 type updateUserData struct {
-	Username    *string `json:"username" validate:"omitnil,min=5,max=50"`
-	DisplayName *string `json:"display_name" validate:"omitnil,min=5,max=150"`
-	Password    *string `json:"password" validate:"omitnil,printascii,min=8"`
+	Username        *string    `json:"username" validate:"omitnil,min=5,max=50"`
+	DisplayName     *string    `json:"display_name" validate:"omitnil,min=5,max=150"`
+	Password        *string    `json:"password" validate:"omitnil,printascii,min=8"`
+	Bio             *string    `json:"bio" validate:"omitnil,max=500,printtext=multiline"`
+	StatusText      *string    `json:"status_text" validate:"omitnil,max=140,printtext"`
+	StatusExpiresAt *time.Time `json:"status_expires_at" validate:"omitnil,gt"`
+	Timezone        *string    `json:"timezone" validate:"omitnil,min=1,max=64,ne=Local,timezone"`
+	Locale          *string    `json:"locale" validate:"omitnil,max=35,bcp47_language_tag"`
 }
 
 func If[T any](cond bool, vTrue, vFalse T) T {
@@ -141,6 +184,15 @@ func If[T any](cond bool, vTrue, vFalse T) T {
 	return vFalse
 }
 
+// valueOr is the value of an optional field, If cannot be used for it as
+// both of its values are evaluated
+func valueOr[T any](p *T, fallback T) T {
+	if p == nil {
+		return fallback
+	}
+	return *p
+}
+
 func handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 	ud := updateUserData{}
 	decoder := json.NewDecoder(r.Body)
@@ -167,29 +219,71 @@ func handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 			slog.Error("error with validator definition", "error", err)
 			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
 		} else {
-			render.RespondValidationFailure(w, validationErrors)
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
 		}
 		return
 	}
 	// find the updated fields
-	user.Username = If(ud.Username != nil, *ud.Username, user.Username)
-	user.DisplayName = If(ud.DisplayName != nil, *ud.DisplayName, user.DisplayName)
-	user.Password = If(ud.Password != nil, auth.SaltyPassword([]byte(*ud.Password), user.PasswordSalt), user.Password)
-	// update in DB
-	queries := database.New(apiCfg.ConnPool)
+	user.Username = valueOr(ud.Username, user.Username)
+	user.DisplayName = valueOr(ud.DisplayName, user.DisplayName)
+	if ud.Password != nil {
+		user.Password = auth.SaltyPassword([]byte(*ud.Password), user.PasswordSalt)
+	}
+	user.Bio = valueOr(ud.Bio, user.Bio)
+	// a new status line replaces the expiry of the old one as well
+	if ud.StatusText != nil {
+		user.StatusText = *ud.StatusText
+		user.StatusExpiresAt = pgtype.Timestamp{}
+	}
+	if ud.StatusExpiresAt != nil {
+		user.StatusExpiresAt = pgtype.Timestamp{Time: ud.StatusExpiresAt.UTC(), Valid: true}
+	}
+	user.Timezone = valueOr(ud.Timezone, user.Timezone)
+	user.Locale = valueOr(ud.Locale, user.Locale)
+	// update in DB, the webhook delivery is written with the change
+	c, err := apiCfg.ConnPool.Acquire(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	defer c.Release()
+	tx, err := c.Begin(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	defer tx.Rollback(r.Context())
+	queries := database.New(tx)
 	updUser, err := queries.UpdateUserDetails(r.Context(), database.UpdateUserDetailsParams{
-		Username:    user.Username,
-		DisplayName: user.DisplayName,
-		Password:    user.Password,
-		UpdatedAt:   time.Now().UTC(),
-		PvtID:       user.PvtID,
+		Username:        user.Username,
+		DisplayName:     user.DisplayName,
+		Password:        user.Password,
+		UpdatedAt:       time.Now().UTC(),
+		Bio:             user.Bio,
+		StatusText:      user.StatusText,
+		StatusExpiresAt: user.StatusExpiresAt,
+		Timezone:        user.Timezone,
+		Locale:          user.Locale,
+		PvtID:           user.PvtID,
 	})
 	if err != nil {
 		slog.Error("could not update user data", "user_id", user.UserID, "user_name", user.Username)
 		render.RespondFailure(w, http.StatusInsufficientStorage, "could not update at this time")
 		return
 	}
-	render.RespondSuccess(w, http.StatusOK, convertToPublicUser(updUser))
+	publicUser := convertToPublicUser(updUser)
+	err = queueWebhookEvent(r.Context(), queries, updUser.PvtID, webhookEventUserUpdated, pgtype.Int8{}, publicUser)
+	if err != nil {
+		slog.Error("could not queue user webhook", "user_id", updUser.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInsufficientStorage, "could not update at this time")
+		return
+	}
+	err = tx.Commit(r.Context())
+	if err != nil {
+		render.RespondFailure(w, http.StatusInsufficientStorage, "could not update at this time")
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, publicUser)
 }
 
 func handleDeleteUser(w http.ResponseWriter, r *http.Request) {
@@ -206,6 +300,211 @@ func handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	render.RespondSuccess(w, http.StatusOK, convertToPublicUser(delUser))
 }
 
+func getUserIdParam(r *http.Request) (pgtype.UUID, error) {
+	userId := pgtype.UUID{}
+	err := userId.Scan(chi.URLParam(r, "user_id"))
+	return userId, err
+}
+
+// visibleToCaller hides users who have blocked the caller by reporting them
+// as not found
+func visibleToCaller(ctx context.Context, queries *database.Queries, caller, user database.User) (database.User, error) {
+	blocked, err := queries.IsBlockedBy(ctx, database.IsBlockedByParams{
+		BlockerPvtID: user.PvtID,
+		BlockedPvtID: caller.PvtID,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+	if blocked {
+		return database.User{}, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+// convertToVisibleUser adds the last seen time to the reduced view when the
+// user's privacy settings allow the caller to see it
+func convertToVisibleUser(ctx context.Context, queries *database.Queries, caller, user database.User) (PublicUserDetails, error) {
+	publicUser := convertToReducedUser(user)
+	settings, err := getUserSettings(ctx, queries, user.PvtID)
+	if err != nil {
+		return publicUser, err
+	}
+	visible, err := isVisibleTo(ctx, queries, settings.LastSeenVisibility, user.PvtID, caller.PvtID)
+	if err != nil {
+		return publicUser, err
+	}
+	if visible {
+		publicUser.LastLoggedIn = &user.LastLoggedIn
+	}
+	return publicUser, nil
+}
+
+func respondUserLookup(w http.ResponseWriter, r *http.Request, queries *database.Queries, caller, user database.User, err error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	} else if err != nil {
+		slog.Error("could not fetch user details", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	publicUser, err := convertToVisibleUser(r.Context(), queries, caller, user)
+	if err != nil {
+		slog.Error("could not fetch user privacy settings", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, publicUser)
+}
+
+func handleGetUserById(w http.ResponseWriter, r *http.Request) {
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	caller := auth.GetUserData(r)
+	slog.Info("looking up user by id", "user_id", userId, "caller_id", caller.UserID)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	user, err := queries.GetUserByUuid(r.Context(), userId)
+	if err == nil {
+		user, err = visibleToCaller(r.Context(), queries, caller, user)
+	}
+	respondUserLookup(w, r, queries, caller, user, err)
+}
+
+func handleGetUserByName(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	caller := auth.GetUserData(r)
+	slog.Info("looking up user by name", "user_name", username, "caller_id", caller.UserID)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	user, err := queries.GetUserByName(r.Context(), username)
+	if err == nil {
+		user, err = visibleToCaller(r.Context(), queries, caller, user)
+	}
+	respondUserLookup(w, r, queries, caller, user, err)
+}
+
+type searchUserData struct {
+	Query string `json:"q" validate:"required,min=1,max=150"`
+	pageParams
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func handleSearchUsers(w http.ResponseWriter, r *http.Request) {
+	page, err := getPageParams(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid pagination parameters")
+		return
+	}
+	sd := searchUserData{
+		Query:      strings.TrimSpace(r.URL.Query().Get("q")),
+		pageParams: page,
+	}
+
+	apiCfg := apiconf.GetConfig(r)
+	// validate incoming data
+	err = apiCfg.Validate.Struct(sd)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	caller := auth.GetUserData(r)
+	slog.Info("searching users", "query", sd.Query, "caller_id", caller.UserID)
+
+	queries := database.New(apiCfg.ConnPool)
+	users, err := queries.SearchUsers(r.Context(), database.SearchUsersParams{
+		Prefix:      likeEscaper.Replace(sd.Query),
+		Query:       sd.Query,
+		CallerPvtID: caller.PvtID,
+		PageLimit:   sd.Limit,
+		PageOffset:  sd.Offset,
+	})
+	if err != nil {
+		slog.Error("could not search users", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	publicUsers := make([]PublicUserDetails, 0, len(users))
+	for _, u := range users {
+		publicUsers = append(publicUsers, convertToReducedUser(u))
+	}
+	render.RespondSuccess(w, http.StatusOK, newPagedResponse(publicUsers, page))
+}
+
+func handleBlockUser(w http.ResponseWriter, r *http.Request) {
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("blocking user", "user_id", user.UserID, "blocked_id", userId)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	blocked, err := queries.GetUserByUuid(r.Context(), userId)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	if blocked.PvtID == user.PvtID {
+		render.RespondFailure(w, http.StatusBadRequest, "cannot block yourself")
+		return
+	}
+	err = queries.BlockUser(r.Context(), database.BlockUserParams{
+		BlockerPvtID: user.PvtID,
+		BlockedPvtID: blocked.PvtID,
+		CreatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		slog.Error("could not block user", "error", err)
+		render.RespondFailure(w, http.StatusInsufficientStorage, "could not block user at this time")
+		return
+	}
+	render.RespondSuccess(w, http.StatusNoContent, nil)
+}
+
+func handleUnblockUser(w http.ResponseWriter, r *http.Request) {
+	userId, err := getUserIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("unblocking user", "user_id", user.UserID, "blocked_id", userId)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	blocked, err := queries.GetUserByUuid(r.Context(), userId)
+	if err != nil {
+		render.RespondFailure(w, http.StatusNotFound, userNotFoundError)
+		return
+	}
+	err = queries.UnblockUser(r.Context(), database.UnblockUserParams{
+		BlockerPvtID: user.PvtID,
+		BlockedPvtID: blocked.PvtID,
+	})
+	if err != nil {
+		slog.Error("could not unblock user", "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, "could not unblock user at this time")
+		return
+	}
+	render.RespondSuccess(w, http.StatusNoContent, nil)
+}
+
 func UserRouter() *chi.Mux {
 	router := chi.NewMux()
 
@@ -213,8 +512,21 @@ func UserRouter() *chi.Mux {
 		r.Get("/", handleGetUserDetail)
 		r.Patch("/", handleUpdateUser)
 		r.Delete("/", handleDeleteUser)
+		r.Put("/avatar", handleUploadAvatar)
+		r.Delete("/avatar", handleDeleteAvatar)
+		r.Get("/settings", handleGetUserSettings)
+		r.Patch("/settings", handleUpdateUserSettings)
+		r.Get("/search", handleSearchUsers)
+		r.Get("/by-name/{username}", handleGetUserByName)
+		r.Post("/presence", handleBatchPresence)
+		r.Get("/{user_id}", handleGetUserById)
+		r.Get("/{user_id}/presence", handleGetPresence)
+		r.Post("/{user_id}/block", handleBlockUser)
+		r.Delete("/{user_id}/block", handleUnblockUser)
 	})
+	router.With(auth.Authentication).Mount("/contacts", ContactRouter())
 	router.Post("/", handleCreateUser)
+	router.Get("/{user_id}/avatar", handleGetAvatar)
 
 	return router
 }
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/auth"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/render"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	webhookNotFoundError               = "could not find webhook"
+	webhookDeliveryNotFoundError       = "could not find failed delivery"
+	maxWebhooksPerUser           int32 = 10
+	webhookSecretBytes                 = 32
+)
+
+// events a webhook can subscribe to, ping is only ever sent on request
+const (
+	webhookEventMessageCreated = "message.created"
+	webhookEventMessageRead    = "message.read"
+	webhookEventUserUpdated    = "user.updated"
+	webhookEventPing           = "ping"
+)
+
+// PublicWebhook is a subscription of the caller, the secret is only shown
+// when the webhook is created
+type PublicWebhook struct {
+	WebhookID           pgtype.UUID `json:"webhook_id"`
+	URL                 string      `json:"url"`
+	Events              []string    `json:"events"`
+	Enabled             bool        `json:"enabled"`
+	ConsecutiveFailures int32       `json:"consecutive_failures"`
+	DisabledAt          *time.Time  `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time   `json:"created_at"`
+	UpdatedAt           time.Time   `json:"updated_at"`
+	Secret              string      `json:"secret,omitempty"`
+}
+
+func convertToPublicWebhook(w database.WebhookSubscription) PublicWebhook {
+	webhook := PublicWebhook{
+		WebhookID:           w.WebhookID,
+		URL:                 w.Url,
+		Events:              w.Events,
+		Enabled:             w.Enabled,
+		ConsecutiveFailures: w.ConsecutiveFailures,
+		CreatedAt:           w.CreatedAt,
+		UpdatedAt:           w.UpdatedAt,
+	}
+	if w.DisabledAt.Valid {
+		webhook.DisabledAt = &w.DisabledAt.Time
+	}
+	return webhook
+}
+
+// PublicWebhookDelivery is an entry of the delivery log, next_attempt_at is
+// only set while the delivery is pending
+type PublicWebhookDelivery struct {
+	DeliveryID     int64                          `json:"delivery_id"`
+	Event          string                         `json:"event"`
+	Status         database.WebhookDeliveryStatus `json:"status"`
+	Attempts       int32                          `json:"attempts"`
+	NextAttemptAt  *time.Time                     `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time                     `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int32                         `json:"response_status,omitempty"`
+	LastError      string                         `json:"last_error,omitempty"`
+	CreatedAt      time.Time                      `json:"created_at"`
+	DeliveredAt    *time.Time                     `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage                `json:"payload"`
+}
+
+func convertToPublicWebhookDelivery(d database.WebhookDelivery) PublicWebhookDelivery {
+	delivery := PublicWebhookDelivery{
+		DeliveryID: d.DeliveryID,
+		Event:      d.Event,
+		Status:     d.Status,
+		Attempts:   d.Attempts,
+		LastError:  d.LastError,
+		CreatedAt:  d.CreatedAt,
+		Payload:    d.Payload,
+	}
+	if d.Status == database.WebhookDeliveryStatusPending {
+		delivery.NextAttemptAt = &d.NextAttemptAt
+	}
+	if d.LastAttemptAt.Valid {
+		delivery.LastAttemptAt = &d.LastAttemptAt.Time
+	}
+	if d.ResponseStatus.Valid {
+		delivery.ResponseStatus = &d.ResponseStatus.Int32
+	}
+	if d.DeliveredAt.Valid {
+		delivery.DeliveredAt = &d.DeliveredAt.Time
+	}
+	return delivery
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, webhookSecretBytes)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func getWebhookIdParam(r *http.Request) (pgtype.UUID, error) {
+	webhookId := pgtype.UUID{}
+	err := webhookId.Scan(chi.URLParam(r, "webhook_id"))
+	return webhookId, err
+}
+
+// getOwnWebhook loads the webhook of the url, webhooks of other users are
+// not found
+func getOwnWebhook(w http.ResponseWriter, r *http.Request, queries *database.Queries, user database.User) (database.WebhookSubscription, bool) {
+	webhookId, err := getWebhookIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid webhook id")
+		return database.WebhookSubscription{}, false
+	}
+	webhook, err := queries.GetWebhook(r.Context(), database.GetWebhookParams{
+		WebhookID:  webhookId,
+		OwnerPvtID: user.PvtID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondFailure(w, http.StatusNotFound, webhookNotFoundError)
+		return webhook, false
+	} else if err != nil {
+		slog.Error("could not fetch webhook", "webhook_id", webhookId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return webhook, false
+	}
+	return webhook, true
+}
+
+// createWebhookData subscribes an endpoint to events about the caller, a
+// secret is made up when none is given
+type createWebhookData struct {
+	URL    string   `json:"url"    validate:"required,http_url,max=2048"`
+	Secret string   `json:"secret" validate:"omitempty,min=16,max=256"`
+	Events []string `json:"events" validate:"required,min=1,unique,dive,oneof=message.created message.read user.updated"`
+}
+
+func handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	data := createWebhookData{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&data)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(data)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("creating webhook", "user_id", user.UserID, "events", data.Events)
+
+	if data.Secret == "" {
+		data.Secret, err = newWebhookSecret()
+	}
+	webhookId, idErr := newUUID()
+	if err != nil || idErr != nil {
+		slog.Error("could not generate webhook identity", "error", errors.Join(err, idErr))
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	queries := database.New(apiCfg.ConnPool)
+	webhook, err := queries.CreateWebhook(r.Context(), database.CreateWebhookParams{
+		WebhookID:   webhookId,
+		OwnerPvtID:  user.PvtID,
+		Url:         data.URL,
+		Secret:      data.Secret,
+		Events:      data.Events,
+		CreatedAt:   time.Now().UTC(),
+		MaxWebhooks: maxWebhooksPerUser,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondFailure(w, http.StatusConflict, fmt.Sprintf("a user can have at most %d webhooks", maxWebhooksPerUser))
+		return
+	} else if err != nil {
+		slog.Error("could not create webhook", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	publicWebhook := convertToPublicWebhook(webhook)
+	publicWebhook.Secret = webhook.Secret
+	render.RespondSuccess(w, http.StatusCreated, publicWebhook)
+}
+
+func handleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	webhooks, err := queries.ListWebhooks(r.Context(), user.PvtID)
+	if err != nil {
+		slog.Error("could not list webhooks", "user_id", user.UserID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	publicWebhooks := make([]PublicWebhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		publicWebhooks = append(publicWebhooks, convertToPublicWebhook(webhook))
+	}
+	render.RespondSuccess(w, http.StatusOK, publicWebhooks)
+}
+
+func handleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	webhook, ok := getOwnWebhook(w, r, queries, user)
+	if !ok {
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToPublicWebhook(webhook))
+}
+
+// updateWebhookData changes the fields that are given. Enabling a webhook
+// starts its failure count over, deliveries that were left pending while it
+// was disabled go out again.
+type updateWebhookData struct {
+	URL     *string  `json:"url"     validate:"omitnil,http_url,max=2048"`
+	Events  []string `json:"events"  validate:"omitnil,min=1,unique,dive,oneof=message.created message.read user.updated"`
+	Enabled *bool    `json:"enabled"`
+}
+
+func handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	data := updateWebhookData{}
+	decoder := json.NewDecoder(r.Body)
+	err := decoder.Decode(&data)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "could not decode data")
+		return
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(data)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	queries := database.New(apiCfg.ConnPool)
+	webhook, ok := getOwnWebhook(w, r, queries, user)
+	if !ok {
+		return
+	}
+	slog.Info("updating webhook", "user_id", user.UserID, "webhook_id", webhook.WebhookID)
+
+	if data.URL != nil {
+		webhook.Url = *data.URL
+	}
+	if data.Events != nil {
+		webhook.Events = data.Events
+	}
+	if data.Enabled != nil {
+		webhook.Enabled = *data.Enabled
+	}
+	updated, err := queries.UpdateWebhook(r.Context(), database.UpdateWebhookParams{
+		Url:        webhook.Url,
+		Events:     webhook.Events,
+		Enabled:    webhook.Enabled,
+		UpdatedAt:  time.Now().UTC(),
+		WebhookID:  webhook.WebhookID,
+		OwnerPvtID: user.PvtID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondFailure(w, http.StatusNotFound, webhookNotFoundError)
+		return
+	} else if err != nil {
+		slog.Error("could not update webhook", "webhook_id", webhook.WebhookID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusOK, convertToPublicWebhook(updated))
+}
+
+// handleDeleteWebhook removes a webhook along with its delivery log
+func handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookId, err := getWebhookIdParam(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid webhook id")
+		return
+	}
+	user := auth.GetUserData(r)
+	slog.Info("deleting webhook", "user_id", user.UserID, "webhook_id", webhookId)
+
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	deleted, err := queries.DeleteWebhook(r.Context(), database.DeleteWebhookParams{
+		WebhookID:  webhookId,
+		OwnerPvtID: user.PvtID,
+	})
+	if err != nil {
+		slog.Error("could not delete webhook", "webhook_id", webhookId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	if deleted == 0 {
+		render.RespondFailure(w, http.StatusNotFound, webhookNotFoundError)
+		return
+	}
+	render.RespondSuccess(w, http.StatusNoContent, nil)
+}
+
+// PingEvent is the data of a ping, sent to check that an endpoint is
+// reachable and verifies signatures
+type PingEvent struct {
+	WebhookID pgtype.UUID `json:"webhook_id"`
+}
+
+// handlePingWebhook queues a ping for the webhook even if it does not
+// subscribe to anything that happened lately. The ping shows up in the
+// delivery log like any other event.
+func handlePingWebhook(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	webhook, ok := getOwnWebhook(w, r, queries, user)
+	if !ok {
+		return
+	}
+	slog.Info("pinging webhook", "user_id", user.UserID, "webhook_id", webhook.WebhookID)
+
+	payload, err := json.Marshal(PingEvent{WebhookID: webhook.WebhookID})
+	if err != nil {
+		slog.Error("could not encode ping", "webhook_id", webhook.WebhookID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	delivery, err := queries.QueueWebhookDelivery(r.Context(), database.QueueWebhookDeliveryParams{
+		WebhookID: webhook.WebhookID,
+		Event:     webhookEventPing,
+		Payload:   payload,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		slog.Error("could not queue ping", "webhook_id", webhook.WebhookID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusAccepted, convertToPublicWebhookDelivery(delivery))
+}
+
+// handleListWebhookDeliveries is the delivery log of a webhook, latest first,
+// optionally only the deliveries with the given status
+func handleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	page, err := getKeysetParams(r)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid pagination parameters")
+		return
+	}
+	status := database.NullWebhookDeliveryStatus{}
+	if val := r.URL.Query().Get("status"); val != "" {
+		status = database.NullWebhookDeliveryStatus{WebhookDeliveryStatus: database.WebhookDeliveryStatus(val), Valid: true}
+		switch status.WebhookDeliveryStatus {
+		case database.WebhookDeliveryStatusPending, database.WebhookDeliveryStatusSucceeded, database.WebhookDeliveryStatusFailed:
+		default:
+			render.RespondFailure(w, http.StatusBadRequest, "invalid status parameter")
+			return
+		}
+	}
+	apiCfg := apiconf.GetConfig(r)
+	err = apiCfg.Validate.Struct(page)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			slog.Error("error with validator definition", "error", err)
+			render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		} else {
+			render.RespondValidationFailure(w, validationErrors, requestTranslator(r))
+		}
+		return
+	}
+	user := auth.GetUserData(r)
+	queries := database.New(apiCfg.ConnPool)
+	webhook, ok := getOwnWebhook(w, r, queries, user)
+	if !ok {
+		return
+	}
+	deliveries, err := queries.ListWebhookDeliveries(r.Context(), database.ListWebhookDeliveriesParams{
+		WebhookID:        webhook.WebhookID,
+		Status:           status,
+		CursorCreatedAt:  page.CursorCreatedAt(),
+		CursorDeliveryID: page.CursorID(),
+		PageLimit:        page.Limit,
+	})
+	if err != nil {
+		slog.Error("could not list webhook deliveries", "webhook_id", webhook.WebhookID, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	publicDeliveries := make([]PublicWebhookDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		publicDeliveries = append(publicDeliveries, convertToPublicWebhookDelivery(d))
+	}
+	render.RespondSuccess(w, http.StatusOK, newKeysetResponse(publicDeliveries, page, func(d PublicWebhookDelivery) keysetCursor {
+		return keysetCursor{CreatedAt: d.CreatedAt, ID: d.DeliveryID}
+	}))
+}
+
+// handleRetryWebhookDelivery gives a failed delivery one more attempt right
+// away
+func handleRetryWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	deliveryId, err := strconv.ParseInt(chi.URLParam(r, "delivery_id"), 10, 64)
+	if err != nil {
+		render.RespondFailure(w, http.StatusBadRequest, "invalid delivery id")
+		return
+	}
+	user := auth.GetUserData(r)
+	apiCfg := apiconf.GetConfig(r)
+	queries := database.New(apiCfg.ConnPool)
+	webhook, ok := getOwnWebhook(w, r, queries, user)
+	if !ok {
+		return
+	}
+	slog.Info("retrying webhook delivery", "user_id", user.UserID, "webhook_id", webhook.WebhookID, "delivery_id", deliveryId)
+
+	delivery, err := queries.RetryWebhookDelivery(r.Context(), database.RetryWebhookDeliveryParams{
+		Now:        time.Now().UTC(),
+		DeliveryID: deliveryId,
+		WebhookID:  webhook.WebhookID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		render.RespondFailure(w, http.StatusNotFound, webhookDeliveryNotFoundError)
+		return
+	} else if err != nil {
+		slog.Error("could not retry webhook delivery", "delivery_id", deliveryId, "error", err)
+		render.RespondFailure(w, http.StatusInternalServerError, internalServerErrorMssg)
+		return
+	}
+	render.RespondSuccess(w, http.StatusAccepted, convertToPublicWebhookDelivery(delivery))
+}
+
+func WebhookRouter() *chi.Mux {
+	router := chi.NewMux()
+
+	router.Post("/", handleCreateWebhook)
+	router.Get("/", handleListWebhooks)
+	router.Get("/{webhook_id}", handleGetWebhook)
+	router.Patch("/{webhook_id}", handleUpdateWebhook)
+	router.Delete("/{webhook_id}", handleDeleteWebhook)
+	router.Post("/{webhook_id}/ping", handlePingWebhook)
+	router.Get("/{webhook_id}/deliveries", handleListWebhookDeliveries)
+	router.Post("/{webhook_id}/deliveries/{delivery_id}/retry", handleRetryWebhookDelivery)
+
+	return router
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Suryarpan/chat-api/internal/apiconf"
+	"github.com/Suryarpan/chat-api/internal/database"
+	"github.com/Suryarpan/chat-api/internal/webhook"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	webhookDispatchInterval        = 5 * time.Second
+	webhookDispatchBatchSize int32 = 50
+	// claimed deliveries are left alone until the slowest batch is sent, one
+	// endpoint timing out on every send at maxWebhookConcurrency at a time,
+	// and recorded. A crashed dispatcher only delays them.
+	webhookLease = time.Duration((webhookDispatchBatchSize+maxWebhookConcurrency-1)/maxWebhookConcurrency)*webhook.SendTimeout + time.Minute
+	// with the backoff the attempts of a delivery are spread over about 15
+	// hours before it is given up
+	maxWebhookAttempts int32 = 12
+	// an endpoint failing this many dispatch rounds in a row is disabled, a
+	// round counts once however many of its deliveries failed
+	maxWebhookFailures int32 = 20
+	// a slow endpoint holds up only this many sends of a batch at a time
+	maxWebhookConcurrency = 4
+	maxWebhookErrorLength = 500
+	webhookDisabledEvent  = "webhook_disabled"
+	// finished deliveries stay in the log for a month
+	webhookLogRetention          = 30 * 24 * time.Hour
+	webhookPrunerInterval        = time.Hour
+	webhookPrunerBatchSize int32 = 1000
+)
+
+// WebhookEnvelope is the body of every delivery, data is the event as the
+// owner of the webhook would see it in the api
+type WebhookEnvelope struct {
+	DeliveryID int64           `json:"delivery_id"`
+	Event      string          `json:"event"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// queueWebhookEvent hands an event to the webhooks of its owner that
+// subscribe to it, nothing is queued for users without one. Events carrying
+// a message name it, the purger strips them once it expires.
+func queueWebhookEvent(ctx context.Context, queries *database.Queries, ownerPvtId int32, event string, mssgId pgtype.Int8, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = queries.QueueWebhookEvent(ctx, database.QueueWebhookEventParams{
+		Event:      event,
+		Payload:    payload,
+		CreatedAt:  time.Now().UTC(),
+		MssgID:     mssgId,
+		OwnerPvtID: ownerPvtId,
+	})
+	return err
+}
+
+// queueMessageWebhooks sends new messages to the webhooks of both
+// participants. It runs in the transaction that writes the messages, so the
+// deliveries exist exactly when the messages do. The owners with a webhook
+// for new messages are looked up once, so a batch costs nothing extra for
+// everybody else.
+func queueMessageWebhooks(ctx context.Context, queries *database.Queries, sent []sentMessage) error {
+	if len(sent) == 0 {
+		return nil
+	}
+	pvtIds := make([]int32, 0, 2*len(sent))
+	for _, m := range sent {
+		pvtIds = append(pvtIds, m.fromPvtId, m.toPvtId)
+	}
+	owners, err := queries.ListWebhookOwners(ctx, database.ListWebhookOwnersParams{
+		OwnerPvtIds: pvtIds,
+		Event:       webhookEventMessageCreated,
+	})
+	if err != nil {
+		return err
+	}
+	subscribed := make(map[int32]bool, len(owners))
+	for _, owner := range owners {
+		subscribed[owner] = true
+	}
+	queue := func(m sentMessage, ownerPvtId int32, viewer pgtype.UUID) error {
+		if !subscribed[ownerPvtId] {
+			return nil
+		}
+		mssgId := pgtype.Int8{Int64: m.content.MssgID, Valid: true}
+		return queueWebhookEvent(ctx, queries, ownerPvtId, webhookEventMessageCreated, mssgId, convertToPublicMessage(m.content, database.User{UserID: viewer}))
+	}
+	for _, m := range sent {
+		err = queue(m, m.toPvtId, m.content.ToUserID)
+		if err == nil && m.fromPvtId != m.toPvtId {
+			err = queue(m, m.fromPvtId, m.content.FromUserID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// queueReadWebhooks tells the sender's webhooks about messages read in bulk,
+// like a single read only when the reader sends read receipts. The messages
+// are only loaded when the sender has a webhook for reads.
+func queueReadWebhooks(ctx context.Context, queries *database.Queries, senderPvtId int32, mssgIds []int64) error {
+	if len(mssgIds) == 0 {
+		return nil
+	}
+	owners, err := queries.ListWebhookOwners(ctx, database.ListWebhookOwnersParams{
+		OwnerPvtIds: []int32{senderPvtId},
+		Event:       webhookEventMessageRead,
+	})
+	if err != nil || len(owners) == 0 {
+		return err
+	}
+	read, err := queries.ListMessagesByIdsPublic(ctx, mssgIds)
+	if err != nil {
+		return err
+	}
+	for _, m := range read {
+		senderView := convertToPublicMessage(m, database.User{UserID: m.FromUserID})
+		if senderView.MssgStatus != database.MessageStatusRead {
+			continue
+		}
+		err = queueWebhookEvent(ctx, queries, senderPvtId, webhookEventMessageRead, pgtype.Int8{Int64: m.MssgID, Valid: true}, senderView)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runWebhookDispatcher sends due deliveries and schedules the failed ones
+// again
+func runWebhookDispatcher(ctx context.Context, apiCfg apiconf.ApiConfig) {
+	runBatches(ctx, "webhook dispatcher", webhookDispatchInterval, webhookDispatchBatchSize, func(ctx context.Context) (int32, error) {
+		return dispatchWebhooks(ctx, apiCfg)
+	})
+}
+
+// runWebhookLogPruner drops finished deliveries once they are old enough
+func runWebhookLogPruner(ctx context.Context, apiCfg apiconf.ApiConfig) {
+	runBatches(ctx, "webhook log pruner", webhookPrunerInterval, webhookPrunerBatchSize, func(ctx context.Context) (int32, error) {
+		queries := database.New(apiCfg.ConnPool)
+		deleted, err := queries.DeleteOldWebhookDeliveries(ctx, database.DeleteOldWebhookDeliveriesParams{
+			CreatedAt: time.Now().UTC().Add(-webhookLogRetention),
+			Limit:     webhookPrunerBatchSize,
+		})
+		return int32(deleted), err
+	})
+}
+
+type webhookAttempt struct {
+	status      int
+	err         error
+	attemptedAt time.Time
+}
+
+// dispatchWebhooks handles one batch of due deliveries. Claiming moves them
+// out of reach of other instances for the lease, the sends run at the same
+// time and are recorded once all are done.
+func dispatchWebhooks(ctx context.Context, apiCfg apiconf.ApiConfig) (int32, error) {
+	queries := database.New(apiCfg.ConnPool)
+	now := time.Now().UTC()
+	due, err := queries.ClaimDueWebhookDeliveries(ctx, database.ClaimDueWebhookDeliveriesParams{
+		LeaseUntil: now.Add(webhookLease),
+		Now:        now,
+		BatchSize:  webhookDispatchBatchSize,
+	})
+	if err != nil || len(due) == 0 {
+		return 0, err
+	}
+	attempts := make([]webhookAttempt, len(due))
+	slots := make(map[pgtype.UUID]chan struct{})
+	wg := sync.WaitGroup{}
+	for n, d := range due {
+		slot, ok := slots[d.WebhookID]
+		if !ok {
+			slot = make(chan struct{}, maxWebhookConcurrency)
+			slots[d.WebhookID] = slot
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slot <- struct{}{}
+			defer func() { <-slot }()
+			body, err := json.Marshal(WebhookEnvelope{
+				DeliveryID: d.DeliveryID,
+				Event:      d.Event,
+				CreatedAt:  d.CreatedAt,
+				Data:       d.Payload,
+			})
+			if err != nil {
+				attempts[n] = webhookAttempt{err: err, attemptedAt: time.Now().UTC()}
+				return
+			}
+			status, err := apiCfg.Webhooks.Send(ctx, webhook.Delivery{
+				ID:     d.DeliveryID,
+				Event:  d.Event,
+				URL:    d.Url,
+				Secret: d.Secret,
+				Body:   body,
+			})
+			attempts[n] = webhookAttempt{status: status, err: err, attemptedAt: time.Now().UTC()}
+		}()
+	}
+	wg.Wait()
+	// sends cut short by a shutdown are not the endpoint's fault, the lease
+	// runs out and they go out again
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	// a webhook with any delivered event in the round is working
+	succeeded := make(map[pgtype.UUID]bool, len(slots))
+	for n, d := range due {
+		err := recordWebhookAttempt(ctx, queries, d, attempts[n])
+		if err != nil {
+			slog.Error("could not record webhook attempt", "delivery_id", d.DeliveryID, "error", err)
+		}
+		succeeded[d.WebhookID] = succeeded[d.WebhookID] || attempts[n].err == nil
+	}
+	for webhookId, ok := range succeeded {
+		err := recordWebhookRound(ctx, apiCfg, queries, webhookId, ok, now)
+		if err != nil {
+			slog.Error("could not record webhook failures", "webhook_id", webhookId, "error", err)
+		}
+	}
+	slog.Debug("dispatched webhooks", "count", len(due))
+	return int32(len(due)), nil
+}
+
+// recordWebhookAttempt writes down how an attempt went. A failed delivery
+// is tried again later until it runs out of attempts.
+func recordWebhookAttempt(ctx context.Context, queries *database.Queries, d database.ClaimDueWebhookDeliveriesRow, a webhookAttempt) error {
+	params := database.RecordWebhookAttemptParams{
+		Status:         database.WebhookDeliveryStatusSucceeded,
+		NextAttemptAt:  a.attemptedAt,
+		AttemptedAt:    pgtype.Timestamp{Time: a.attemptedAt, Valid: true},
+		ResponseStatus: pgtype.Int4{Int32: int32(a.status), Valid: a.status != 0},
+		DeliveryID:     d.DeliveryID,
+	}
+	if a.err == nil {
+		params.DeliveredAt = pgtype.Timestamp{Time: a.attemptedAt, Valid: true}
+		return queries.RecordWebhookAttempt(ctx, params)
+	}
+
+	attempts := d.Attempts + 1
+	params.Status = database.WebhookDeliveryStatusPending
+	params.NextAttemptAt = a.attemptedAt.Add(webhook.Backoff(attempts))
+	if attempts >= maxWebhookAttempts {
+		params.Status = database.WebhookDeliveryStatusFailed
+	}
+	params.LastError = a.err.Error()
+	if len(params.LastError) > maxWebhookErrorLength {
+		params.LastError = strings.ToValidUTF8(params.LastError[:maxWebhookErrorLength], "")
+	}
+	return queries.RecordWebhookAttempt(ctx, params)
+}
+
+// recordWebhookRound resets the failures of a webhook that delivered
+// something in the round, otherwise the round counts as one failure towards
+// disabling it
+func recordWebhookRound(ctx context.Context, apiCfg apiconf.ApiConfig, queries *database.Queries, webhookId pgtype.UUID, succeeded bool, now time.Time) error {
+	if succeeded {
+		return queries.ResetWebhookFailures(ctx, webhookId)
+	}
+	sub, err := queries.CountWebhookFailure(ctx, database.CountWebhookFailureParams{
+		MaxFailures: maxWebhookFailures,
+		Now:         now,
+		WebhookID:   webhookId,
+	})
+	if err != nil {
+		return err
+	}
+	// only the round that crossed the limit reports it, another instance
+	// may count a round of its own after it
+	if !sub.Enabled && sub.ConsecutiveFailures == maxWebhookFailures {
+		slog.Warn("disabled failing webhook", "webhook_id", sub.WebhookID, "owner_pvt_id", sub.OwnerPvtID)
+		err = apiCfg.Hub.Publish(ctx, []int32{sub.OwnerPvtID}, webhookDisabledEvent, convertToPublicWebhook(sub))
+		if err != nil {
+			slog.Warn("could not publish disabled webhook", "webhook_id", sub.WebhookID, "error", err)
+		}
+	}
+	return nil
+}
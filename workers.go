@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// runBatches calls work every interval until the context is done. A full
+// batch means more work may be waiting, so work is called again right away.
+func runBatches(ctx context.Context, name string, interval time.Duration, batchSize int32, work func(context.Context) (int32, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		done, err := work(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("background work failed", "worker", name, "error", err)
+		}
+		if done == batchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}